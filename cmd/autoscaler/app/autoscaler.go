@@ -21,7 +21,10 @@ such restriction.
 package app
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/v3io/scaler/pkg/autoscaler"
@@ -34,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/metrics/pkg/client/custom_metrics"
@@ -43,7 +47,11 @@ func Run(kubeconfigPath string,
 	namespace string,
 	scaleInterval time.Duration,
 	metricsResourceKind string,
-	metricsResourceGroup string) error {
+	metricsResourceGroup string,
+	stuckScaleEventThreshold time.Duration,
+	stuckScaleEventAction string,
+	maxWarmResources int,
+	warmResourcesBudgetScope string) error {
 	autoScalerOptions := scalertypes.AutoScalerOptions{
 		Namespace:     namespace,
 		ScaleInterval: scalertypes.Duration{Duration: scaleInterval},
@@ -51,6 +59,12 @@ func Run(kubeconfigPath string,
 			Kind:  metricsResourceKind,
 			Group: metricsResourceGroup,
 		},
+		StuckScaleEventThreshold: scalertypes.Duration{Duration: stuckScaleEventThreshold},
+		StuckScaleEventAction:    scalertypes.StuckScaleEventAction(stuckScaleEventAction),
+		WarmResourcesBudget: scalertypes.WarmResourcesBudget{
+			MaxWarmResources: maxWarmResources,
+			Scope:            scalertypes.WarmResourcesBudgetScope(warmResourcesBudgetScope),
+		},
 	}
 
 	pluginLoader, err := pluginloader.New()
@@ -78,6 +92,12 @@ func Run(kubeconfigPath string,
 		return errors.Wrap(err, "Failed to get client configuration")
 	}
 
+	if autoScalerOptions.KubeClientSet == nil {
+		if autoScalerOptions.KubeClientSet, err = kubernetes.NewForConfig(restConfig); err != nil {
+			return errors.Wrap(err, "Failed to create k8s client set")
+		}
+	}
+
 	newScaler, err := createAutoScaler(restConfig, resourceScaler, autoScalerOptions)
 	if err != nil {
 		return errors.Wrap(err, "Failed to create scaler")
@@ -87,7 +107,20 @@ func Run(kubeconfigPath string,
 		return errors.Wrap(err, "Failed to start scaler")
 	}
 
-	select {}
+	waitForTerminationSignal()
+
+	// finish the scale check in progress and the scalings it started, so that no resource is left mid scale
+	if err = newScaler.Stop(); err != nil {
+		return errors.Wrap(err, "Failed to stop scaler")
+	}
+
+	return nil
+}
+
+func waitForTerminationSignal() {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-signalCtx.Done()
 }
 
 func createAutoScaler(restConfig *rest.Config,
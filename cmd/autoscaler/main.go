@@ -37,6 +37,10 @@ func main() {
 	scaleInterval := flag.Duration("scale-interval", time.Minute, "Interval to call check scale function")
 	metricsResourceKind := flag.String("metrics-resource-kind", "", "Resource kind (e.g. NuclioFunction)")
 	metricsResourceGroup := flag.String("metrics-resource-group", "", "Resource group (e.g. nuclio.io)")
+	stuckScaleEventThreshold := flag.Duration("stuck-scale-event-threshold", 0, "Time after which a started scale transition is considered stuck (0 to disable)")
+	stuckScaleEventAction := flag.String("stuck-scale-event-action", "retryAndAlert", "Action to take on stuck resources (retry, alert or retryAndAlert)")
+	maxWarmResources := flag.Int("max-warm-resources", 0, "Maximal total cost of warm resources, beyond which the least recently used are scaled to zero (0 to disable)")
+	warmResourcesBudgetScope := flag.String("warm-resources-budget-scope", "namespace", "Scope of the warm resources budget (namespace or global)")
 	flag.Parse()
 
 	*namespace = common.GetNamespace(*namespace)
@@ -45,7 +49,11 @@ func main() {
 		*namespace,
 		*scaleInterval,
 		*metricsResourceKind,
-		*metricsResourceGroup); err != nil {
+		*metricsResourceGroup,
+		*stuckScaleEventThreshold,
+		*stuckScaleEventAction,
+		*maxWarmResources,
+		*warmResourcesBudgetScope); err != nil {
 		errors.PrintErrorStack(os.Stderr, err, 5)
 
 		os.Exit(1)
@@ -21,7 +21,13 @@ such restriction.
 package app
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/v3io/scaler/pkg/common"
@@ -34,50 +40,268 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-func Run(kubeconfigPath string,
-	namespace string,
-	targetNameHeader string,
-	targetPathHeader string,
-	targetPort int,
-	listenAddress string,
-	resourceReadinessTimeout string,
-	multiTargetStrategy string) error {
+// Options holds the command line arguments of the DLX. durations are unparsed, as given on the command line
+type Options struct {
+	KubeconfigPath                    string
+	Namespace                         string
+	TargetNameHeader                  string
+	TargetPathHeader                  string
+	TargetPort                        int
+	ListenAddress                     string
+	ResourceReadinessTimeout          string
+	MultiTargetStrategy               string
+	WaitForDependencies               bool
+	MaxWarmResources                  int
+	WarmResourcesBudgetScope          string
+	WarmResourcesBudgetExceededPolicy string
+	WakeRequestsPerReplica            int
+	MaxWakeReplicas                   int
+	MaxWaitingRequestsPerResource     int
+	MaxWaitingRequests                int
+	ReadinessCacheTTL                 string
+	ProxyMaxIdleConns                 int
+	ProxyMaxIdleConnsPerHost          int
+	ProxyIdleConnTimeout              string
+	ProxyDialTimeout                  string
+	ProxyTLSHandshakeTimeout          string
+	ProxyResponseHeaderTimeout        string
+	ReadinessProbeType                string
+	ReadinessProbePath                string
+	ReadinessProbePort                int
+	ReadinessProbeInterval            string
+	ReadinessProbeTimeout             string
+	ProxyWarmUpRetryWindow            string
+	ProxyRetryMaxBodySize             int64
+	ProxyRetryBackoff                 string
+	ProxyRetryMaxBackoff              string
+	WakeSelectedTargetOnly            bool
+	LazyWakeOtherTargets              bool
+	StickySessionKeySource            string
+	StickySessionKeyName              string
+	StickySessionAffinityCookieName   string
+	StickySessionAffinityCookieMaxAge string
+	CanaryByHeader                    string
+	CanaryByHeaderValue               string
+	CanaryByHeaderPattern             string
+	CanaryByCookie                    string
+	EnableHTTP2                       bool
+	TCPProxyListeners                 string
+	TCPProxyDialTimeout               string
+	TLSCertFile                       string
+	TLSKeyFile                        string
+	TLSIngressSecrets                 bool
+	TLSReloadInterval                 string
+	ShutdownTimeout                   string
+	ServerReadTimeout                 string
+	ServerReadHeaderTimeout           string
+	ServerWriteTimeout                string
+	ServerIdleTimeout                 string
+	ServerMaxHeaderBytes              int
+	ServerDisableKeepAlives           bool
+	ErrorPageTemplateFile             string
+	ErrorPageHostTemplateFiles        string
+	ErrorPageRefreshInterval          string
+	ErrorPageWakeGracePeriod          string
+	AsyncWakeRoutes                   string
+	AsyncWakeQueueDir                 string
+	AsyncWakeMaxQueuedBodySize        int64
+	ControlAPITokenFile               string
+}
+
+func Run(options Options) error {
 	pluginLoader, err := pluginloader.New()
 	if err != nil {
 		return errors.Wrap(err, "Failed to initialize plugin loader")
 	}
 
-	resourceScaler, err := pluginLoader.Load(kubeconfigPath, namespace)
+	resourceScaler, err := pluginLoader.Load(options.KubeconfigPath, options.Namespace)
 	if err != nil {
 		return errors.Wrap(err, "Failed to load plugin")
 	}
 
-	resourceReadinessTimeoutDuration, err := time.ParseDuration(resourceReadinessTimeout)
+	resourceReadinessTimeoutDuration, err := time.ParseDuration(options.ResourceReadinessTimeout)
 	if err != nil {
 		return errors.Wrap(err, "Failed to parse resource readiness timeout")
 	}
 
+	readinessCacheTTLDuration, err := time.ParseDuration(options.ReadinessCacheTTL)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse readiness cache TTL")
+	}
+
+	proxyTransportOptions, err := parseProxyTransportOptions(options.ProxyMaxIdleConns,
+		options.ProxyMaxIdleConnsPerHost,
+		options.ProxyIdleConnTimeout,
+		options.ProxyDialTimeout,
+		options.ProxyTLSHandshakeTimeout,
+		options.ProxyResponseHeaderTimeout)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse proxy transport options")
+	}
+
+	readinessProbeIntervalDuration, err := time.ParseDuration(options.ReadinessProbeInterval)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse readiness probe interval")
+	}
+
+	readinessProbeTimeoutDuration, err := time.ParseDuration(options.ReadinessProbeTimeout)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse readiness probe timeout")
+	}
+
+	proxyRetryOptions, err := parseProxyRetryOptions(options.ProxyWarmUpRetryWindow,
+		options.ProxyRetryMaxBodySize,
+		options.ProxyRetryBackoff,
+		options.ProxyRetryMaxBackoff)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse proxy retry options")
+	}
+
+	stickySessionAffinityCookieMaxAgeDuration, err := time.ParseDuration(options.StickySessionAffinityCookieMaxAge)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse sticky session affinity cookie max age")
+	}
+
+	parsedTCPProxyListeners, err := parseTCPProxyListeners(options.TCPProxyListeners)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse TCP proxy listeners")
+	}
+
+	tcpProxyDialTimeoutDuration, err := time.ParseDuration(options.TCPProxyDialTimeout)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse TCP proxy dial timeout")
+	}
+
+	tlsReloadIntervalDuration, err := time.ParseDuration(options.TLSReloadInterval)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse TLS reload interval")
+	}
+
+	shutdownTimeoutDuration, err := time.ParseDuration(options.ShutdownTimeout)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse shutdown timeout")
+	}
+
+	serverOptions, err := parseServerOptions(options.ServerReadTimeout,
+		options.ServerReadHeaderTimeout,
+		options.ServerWriteTimeout,
+		options.ServerIdleTimeout,
+		options.ServerMaxHeaderBytes,
+		options.ServerDisableKeepAlives)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse server options")
+	}
+
+	parsedErrorPageHostTemplateFiles, err := parseHostTemplateFiles(options.ErrorPageHostTemplateFiles)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse error page host template files")
+	}
+
+	errorPageRefreshIntervalDuration, err := time.ParseDuration(options.ErrorPageRefreshInterval)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse error page refresh interval")
+	}
+
+	errorPageWakeGracePeriodDuration, err := time.ParseDuration(options.ErrorPageWakeGracePeriod)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse error page wake grace period")
+	}
+
+	parsedAsyncWakeRoutes, err := parseAsyncRoutes(options.AsyncWakeRoutes)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse async wake routes")
+	}
+
+	var controlAPIToken string
+	if options.ControlAPITokenFile != "" {
+		controlAPITokenContents, err := os.ReadFile(options.ControlAPITokenFile)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read control API token file")
+		}
+		if controlAPIToken = strings.TrimSpace(string(controlAPITokenContents)); controlAPIToken == "" {
+			return errors.New("Control API token file is empty")
+		}
+	}
+
 	dlxOptions := scalertypes.DLXOptions{
-		TargetNameHeader:         targetNameHeader,
-		TargetPathHeader:         targetPathHeader,
-		TargetPort:               targetPort,
-		ListenAddress:            listenAddress,
-		Namespace:                namespace,
+		TargetNameHeader:         options.TargetNameHeader,
+		TargetPathHeader:         options.TargetPathHeader,
+		TargetPort:               options.TargetPort,
+		ListenAddress:            options.ListenAddress,
+		Namespace:                options.Namespace,
 		ResourceReadinessTimeout: scalertypes.Duration{Duration: resourceReadinessTimeoutDuration},
-		MultiTargetStrategy:      scalertypes.MultiTargetStrategy(multiTargetStrategy),
+		MultiTargetStrategy:      scalertypes.MultiTargetStrategy(options.MultiTargetStrategy),
+		WaitForDependencies:      options.WaitForDependencies,
+		WarmResourcesBudget: scalertypes.WarmResourcesBudget{
+			MaxWarmResources: options.MaxWarmResources,
+			Scope:            scalertypes.WarmResourcesBudgetScope(options.WarmResourcesBudgetScope),
+			ExceededPolicy:   scalertypes.WarmResourcesBudgetExceededPolicy(options.WarmResourcesBudgetExceededPolicy),
+		},
+		WakeRequestsPerReplica:        options.WakeRequestsPerReplica,
+		MaxWakeReplicas:               options.MaxWakeReplicas,
+		MaxWaitingRequestsPerResource: options.MaxWaitingRequestsPerResource,
+		MaxWaitingRequests:            options.MaxWaitingRequests,
+		ReadinessCacheTTL:             scalertypes.Duration{Duration: readinessCacheTTLDuration},
+		ProxyTransport:                proxyTransportOptions,
+		ReadinessProbe: scalertypes.ReadinessProbeOptions{
+			Type:     scalertypes.ReadinessProbeType(options.ReadinessProbeType),
+			Path:     options.ReadinessProbePath,
+			Port:     options.ReadinessProbePort,
+			Interval: scalertypes.Duration{Duration: readinessProbeIntervalDuration},
+			Timeout:  scalertypes.Duration{Duration: readinessProbeTimeoutDuration},
+		},
+		ProxyRetry:             proxyRetryOptions,
+		WakeSelectedTargetOnly: options.WakeSelectedTargetOnly,
+		LazyWakeOtherTargets:   options.LazyWakeOtherTargets,
+		StickySession: scalertypes.StickySessionOptions{
+			KeySource:            scalertypes.StickySessionKeySource(options.StickySessionKeySource),
+			KeyName:              options.StickySessionKeyName,
+			AffinityCookieName:   options.StickySessionAffinityCookieName,
+			AffinityCookieMaxAge: scalertypes.Duration{Duration: stickySessionAffinityCookieMaxAgeDuration},
+		},
+		CanaryOverride: scalertypes.CanaryOverrideOptions{
+			Header:        options.CanaryByHeader,
+			HeaderValue:   options.CanaryByHeaderValue,
+			HeaderPattern: options.CanaryByHeaderPattern,
+			Cookie:        options.CanaryByCookie,
+		},
+		EnableHTTP2:         options.EnableHTTP2,
+		TCPProxyListeners:   parsedTCPProxyListeners,
+		TCPProxyDialTimeout: scalertypes.Duration{Duration: tcpProxyDialTimeoutDuration},
+		TLS: scalertypes.TLSOptions{
+			CertFile:       options.TLSCertFile,
+			KeyFile:        options.TLSKeyFile,
+			IngressSecrets: options.TLSIngressSecrets,
+			ReloadInterval: scalertypes.Duration{Duration: tlsReloadIntervalDuration},
+		},
+		Server: serverOptions,
+		ErrorPage: scalertypes.ErrorPageOptions{
+			TemplateFile:        options.ErrorPageTemplateFile,
+			HostTemplateFiles:   parsedErrorPageHostTemplateFiles,
+			RefreshInterval:     scalertypes.Duration{Duration: errorPageRefreshIntervalDuration},
+			WakePageGracePeriod: scalertypes.Duration{Duration: errorPageWakeGracePeriodDuration},
+		},
+		AsyncWake: scalertypes.AsyncWakeOptions{
+			Routes:            parsedAsyncWakeRoutes,
+			QueueDir:          options.AsyncWakeQueueDir,
+			MaxQueuedBodySize: options.AsyncWakeMaxQueuedBodySize,
+		},
+		ControlAPI: scalertypes.ControlAPIOptions{
+			Token: controlAPIToken,
+		},
 	}
 
-	// see if resource scaler wants to override the arguments
+	// see if resource scaler wants to override the arguments - the options it leaves unset keep their command line values
 	resourceScalerConfig, err := resourceScaler.GetConfig()
 	if err != nil {
 		return errors.Wrap(err, "Failed to get resource scaler config")
 	}
 
 	if resourceScalerConfig != nil {
-		dlxOptions = resourceScalerConfig.DLXOptions
+		dlxOptions = mergeDLXOptions(resourceScalerConfig.DLXOptions, dlxOptions)
 	}
 
-	restConfig, err := common.GetClientConfig(kubeconfigPath)
+	restConfig, err := common.GetClientConfig(options.KubeconfigPath)
 	if err != nil {
 		return errors.Wrap(err, "Failed to get client configuration")
 	}
@@ -96,7 +320,236 @@ func Run(kubeconfigPath string,
 		return errors.Wrap(err, "Failed to start dlx")
 	}
 
-	select {}
+	waitForTerminationSignal()
+
+	// let the in-flight requests finish, e.g. while the pod is replaced by a rolling update
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutDuration)
+	defer cancel()
+	if err := newDLX.Stop(shutdownCtx); err != nil {
+		return errors.Wrap(err, "Failed to stop dlx gracefully")
+	}
+
+	return nil
+}
+
+func waitForTerminationSignal() {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-signalCtx.Done()
+}
+
+// mergeDLXOptions returns the options of the resource scaler, with the options it leaves unset taken from the command
+// line
+func mergeDLXOptions(resourceScalerOptions scalertypes.DLXOptions,
+	commandLineOptions scalertypes.DLXOptions) scalertypes.DLXOptions {
+	mergedOptions := resourceScalerOptions
+	fillUnsetFields(reflect.ValueOf(&mergedOptions).Elem(), reflect.ValueOf(commandLineOptions))
+	return mergedOptions
+}
+
+// fillUnsetFields sets the zero fields of a struct to the fields of another struct of the same type, filling the
+// fields of nested structs one by one
+func fillUnsetFields(value reflect.Value, otherValue reflect.Value) {
+	for fieldIndex := range value.NumField() {
+		field := value.Field(fieldIndex)
+		if !field.CanSet() {
+			continue
+		}
+
+		otherField := otherValue.Field(fieldIndex)
+		switch {
+		case field.Kind() == reflect.Struct:
+			fillUnsetFields(field, otherField)
+		case field.IsZero():
+			field.Set(otherField)
+		}
+	}
+}
+
+func parseProxyTransportOptions(maxIdleConns int,
+	maxIdleConnsPerHost int,
+	idleConnTimeout string,
+	dialTimeout string,
+	tlsHandshakeTimeout string,
+	responseHeaderTimeout string) (scalertypes.ProxyTransportOptions, error) {
+	proxyTransportOptions := scalertypes.ProxyTransportOptions{
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+	}
+
+	for _, duration := range []struct {
+		name   string
+		value  string
+		target *scalertypes.Duration
+	}{
+		{"idle connection timeout", idleConnTimeout, &proxyTransportOptions.IdleConnTimeout},
+		{"dial timeout", dialTimeout, &proxyTransportOptions.DialTimeout},
+		{"TLS handshake timeout", tlsHandshakeTimeout, &proxyTransportOptions.TLSHandshakeTimeout},
+		{"response header timeout", responseHeaderTimeout, &proxyTransportOptions.ResponseHeaderTimeout},
+	} {
+		parsedDuration, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return proxyTransportOptions, errors.Wrapf(err, "Failed to parse %s", duration.name)
+		}
+		duration.target.Duration = parsedDuration
+	}
+
+	return proxyTransportOptions, nil
+}
+
+func parseProxyRetryOptions(warmUpWindow string,
+	maxBodySize int64,
+	backoff string,
+	maxBackoff string) (scalertypes.ProxyRetryOptions, error) {
+	proxyRetryOptions := scalertypes.ProxyRetryOptions{
+		MaxBodySize: maxBodySize,
+	}
+
+	for _, duration := range []struct {
+		name   string
+		value  string
+		target *scalertypes.Duration
+	}{
+		{"warm-up window", warmUpWindow, &proxyRetryOptions.WarmUpWindow},
+		{"backoff", backoff, &proxyRetryOptions.Backoff},
+		{"max backoff", maxBackoff, &proxyRetryOptions.MaxBackoff},
+	} {
+		parsedDuration, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return proxyRetryOptions, errors.Wrapf(err, "Failed to parse %s", duration.name)
+		}
+		duration.target.Duration = parsedDuration
+	}
+
+	return proxyRetryOptions, nil
+}
+
+func parseServerOptions(readTimeout string,
+	readHeaderTimeout string,
+	writeTimeout string,
+	idleTimeout string,
+	maxHeaderBytes int,
+	disableKeepAlives bool) (scalertypes.ServerOptions, error) {
+	serverOptions := scalertypes.ServerOptions{
+		MaxHeaderBytes:    maxHeaderBytes,
+		DisableKeepAlives: disableKeepAlives,
+	}
+
+	for _, duration := range []struct {
+		name   string
+		value  string
+		target *scalertypes.Duration
+	}{
+		{"read timeout", readTimeout, &serverOptions.ReadTimeout},
+		{"read header timeout", readHeaderTimeout, &serverOptions.ReadHeaderTimeout},
+		{"write timeout", writeTimeout, &serverOptions.WriteTimeout},
+		{"idle timeout", idleTimeout, &serverOptions.IdleTimeout},
+	} {
+		parsedDuration, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return serverOptions, errors.Wrapf(err, "Failed to parse %s", duration.name)
+		}
+		duration.target.Duration = parsedDuration
+	}
+
+	return serverOptions, nil
+}
+
+// parseTCPProxyListeners parses comma delimited listeners, each as <listen address>=<resource name>:<target port>
+func parseTCPProxyListeners(value string) ([]scalertypes.TCPProxyListener, error) {
+	var tcpProxyListeners []scalertypes.TCPProxyListener
+	for _, listenerValue := range strings.Split(value, ",") {
+		listenerValue = strings.TrimSpace(listenerValue)
+		if listenerValue == "" {
+			continue
+		}
+
+		listenAddress, target, found := strings.Cut(listenerValue, "=")
+		if !found {
+			return nil, errors.Errorf("Invalid TCP proxy listener: %s", listenerValue)
+		}
+
+		resourceName, targetPort, found := strings.Cut(target, ":")
+		if !found {
+			return nil, errors.Errorf("TCP proxy listener %s has no target port", listenerValue)
+		}
+
+		parsedTargetPort, err := strconv.Atoi(targetPort)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse target port of TCP proxy listener %s", listenerValue)
+		}
+
+		tcpProxyListeners = append(tcpProxyListeners, scalertypes.TCPProxyListener{
+			ListenAddress: listenAddress,
+			ResourceName:  resourceName,
+			TargetPort:    parsedTargetPort,
+		})
+	}
+
+	return tcpProxyListeners, nil
+}
+
+// parseHostTemplateFiles parses comma delimited template files, each as <host>=<template file path>
+func parseHostTemplateFiles(value string) (map[string]string, error) {
+	hostTemplateFiles := map[string]string{}
+	for _, hostTemplateFileValue := range strings.Split(value, ",") {
+		hostTemplateFileValue = strings.TrimSpace(hostTemplateFileValue)
+		if hostTemplateFileValue == "" {
+			continue
+		}
+
+		host, templateFile, found := strings.Cut(hostTemplateFileValue, "=")
+		if !found || host == "" || templateFile == "" {
+			return nil, errors.Errorf("Invalid host template file: %s", hostTemplateFileValue)
+		}
+		hostTemplateFiles[host] = templateFile
+	}
+
+	return hostTemplateFiles, nil
+}
+
+// parseAsyncRoutes parses comma delimited routes, each as <host><path prefix>[=<status>[:queue]]
+func parseAsyncRoutes(value string) ([]scalertypes.AsyncRoute, error) {
+	var asyncRoutes []scalertypes.AsyncRoute
+	for _, routeValue := range strings.Split(value, ",") {
+		routeValue = strings.TrimSpace(routeValue)
+		if routeValue == "" {
+			continue
+		}
+
+		route, response, _ := strings.Cut(routeValue, "=")
+		var asyncRoute scalertypes.AsyncRoute
+		if slashIndex := strings.Index(route, "/"); slashIndex >= 0 {
+			asyncRoute.Host = route[:slashIndex]
+			asyncRoute.PathPrefix = route[slashIndex:]
+		} else {
+			asyncRoute.Host = route
+		}
+		if asyncRoute.Host == "" && asyncRoute.PathPrefix == "" {
+			return nil, errors.Errorf("Async route %s has neither a host nor a path prefix", routeValue)
+		}
+
+		if response != "" {
+			status, mode, _ := strings.Cut(response, ":")
+			parsedStatus, err := strconv.Atoi(status)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to parse status of async route %s", routeValue)
+			}
+			asyncRoute.Status = parsedStatus
+
+			switch mode {
+			case "":
+			case "queue":
+				asyncRoute.QueueRequests = true
+			default:
+				return nil, errors.Errorf("Invalid mode of async route %s: %s", routeValue, mode)
+			}
+		}
+
+		asyncRoutes = append(asyncRoutes, asyncRoute)
+	}
+
+	return asyncRoutes, nil
 }
 
 func createDLX(
@@ -31,26 +31,76 @@ import (
 )
 
 func main() {
-	kubeconfigPath := flag.String("kubeconfig-path", os.Getenv("KUBECONFIG"), "Path of kubeconfig file")
-	namespace := flag.String("namespace", "", "Kubernetes namespace")
-	targetNameHeader := flag.String("target-name-header", "", "Name of the header that holds information on target name")
-	targetPathHeader := flag.String("target-path-header", "", "Name of the header that holds information on target path")
-	targetPort := flag.Int("target-port", 0, "Name of the header that holds information on target port")
-	listenAddress := flag.String("listen-address", ":8090", "Address to listen upon for http proxy")
-	resourceReadinessTimeout := flag.String("resource-readiness-timeout", "5m", "maximum wait time for the resource to be ready")
-	multiTargetStrategy := flag.String("multi-target-strategy", "random", "Strategy for selecting to which target to send the request")
+	options := app.Options{}
+	flag.StringVar(&options.KubeconfigPath, "kubeconfig-path", os.Getenv("KUBECONFIG"), "Path of kubeconfig file")
+	flag.StringVar(&options.Namespace, "namespace", "", "Kubernetes namespace")
+	flag.StringVar(&options.TargetNameHeader, "target-name-header", "", "Name of the header that holds information on target name")
+	flag.StringVar(&options.TargetPathHeader, "target-path-header", "", "Name of the header that holds information on target path")
+	flag.IntVar(&options.TargetPort, "target-port", 0, "Name of the header that holds information on target port")
+	flag.StringVar(&options.ListenAddress, "listen-address", ":8090", "Address to listen upon for http proxy")
+	flag.StringVar(&options.ResourceReadinessTimeout, "resource-readiness-timeout", "5m", "maximum wait time for the resource to be ready")
+	flag.StringVar(&options.MultiTargetStrategy, "multi-target-strategy", "random", "Strategy for selecting to which target to send the request (random, primary, canary, failover or sticky)")
+	flag.BoolVar(&options.WaitForDependencies, "wait-for-dependencies", false, "Wait for the dependencies of a resource to be ready before proxying requests to it")
+	flag.IntVar(&options.MaxWarmResources, "max-warm-resources", 0, "Maximal total cost of warm resources, beyond which wake-ups are rejected or queued (0 to disable)")
+	flag.StringVar(&options.WarmResourcesBudgetScope, "warm-resources-budget-scope", "namespace", "Scope of the warm resources budget (namespace or global)")
+	flag.StringVar(&options.WarmResourcesBudgetExceededPolicy, "warm-resources-budget-exceeded-policy", "reject", "What to do with wake-ups beyond the warm resources budget (reject or queue)")
+	flag.IntVar(&options.WakeRequestsPerReplica, "wake-requests-per-replica", 0, "Scale a woken resource to a replica per this many requests queued while it was waking (0 to disable)")
+	flag.IntVar(&options.MaxWakeReplicas, "max-wake-replicas", 1, "Maximal replicas to scale a woken resource to for queued requests, unless set by the resource")
+	flag.IntVar(&options.MaxWaitingRequestsPerResource, "max-waiting-requests-per-resource", 0, "Maximal requests waiting for a single resource to start (0 for unbounded)")
+	flag.IntVar(&options.MaxWaitingRequests, "max-waiting-requests", 0, "Maximal requests waiting for all resources to start (0 for unbounded)")
+	flag.StringVar(&options.ReadinessCacheTTL, "readiness-cache-ttl", "0", "How long a started resource is proxied to without starting it again, unless its endpoints change (0 to disable)")
+	flag.IntVar(&options.ProxyMaxIdleConns, "proxy-max-idle-conns", 100, "Maximal idle connections kept by the proxy to all targets")
+	flag.IntVar(&options.ProxyMaxIdleConnsPerHost, "proxy-max-idle-conns-per-host", 100, "Maximal idle connections kept by the proxy to a single target")
+	flag.StringVar(&options.ProxyIdleConnTimeout, "proxy-idle-conn-timeout", "90s", "How long an idle proxy connection is kept open")
+	flag.StringVar(&options.ProxyDialTimeout, "proxy-dial-timeout", "30s", "Maximal wait time for a proxy connection to a target")
+	flag.StringVar(&options.ProxyTLSHandshakeTimeout, "proxy-tls-handshake-timeout", "10s", "Maximal wait time for a proxy TLS handshake with a target")
+	flag.StringVar(&options.ProxyResponseHeaderTimeout, "proxy-response-header-timeout", "0", "Maximal wait time for the response headers of a target (0 for unbounded)")
+	flag.StringVar(&options.ReadinessProbeType, "readiness-probe-type", "none", "How to verify that a scaled up resource accepts requests (none, http or tcp)")
+	flag.StringVar(&options.ReadinessProbePath, "readiness-probe-path", "", "Path to GET with an http readiness probe")
+	flag.IntVar(&options.ReadinessProbePort, "readiness-probe-port", 0, "Port of the resource service to probe (defaults to the target port)")
+	flag.StringVar(&options.ReadinessProbeInterval, "readiness-probe-interval", "1s", "Interval between readiness probe attempts")
+	flag.StringVar(&options.ReadinessProbeTimeout, "readiness-probe-timeout", "1s", "Maximal wait time for a single readiness probe attempt")
+	flag.StringVar(&options.ProxyWarmUpRetryWindow, "proxy-warm-up-retry-window", "0", "How long after a resource was woken up failed requests to it are retried (0 to disable)")
+	flag.Int64Var(&options.ProxyRetryMaxBodySize, "proxy-retry-max-body-size", 1024*1024, "Maximal size of a request body buffered for retries")
+	flag.StringVar(&options.ProxyRetryBackoff, "proxy-retry-backoff", "100ms", "Initial backoff between retries of a request")
+	flag.StringVar(&options.ProxyRetryMaxBackoff, "proxy-retry-max-backoff", "1s", "Maximal backoff between retries of a request")
+	flag.BoolVar(&options.WakeSelectedTargetOnly, "wake-selected-target-only", false, "Wake only the target selected for a request to a multi target route")
+	flag.BoolVar(&options.LazyWakeOtherTargets, "lazy-wake-other-targets", false, "Wake the targets not selected for a request in the background, when waking only the selected target")
+	flag.StringVar(&options.StickySessionKeySource, "sticky-session-key-source", "clientIP", "What identifies a session with the sticky strategy (cookie, header or clientIP)")
+	flag.StringVar(&options.StickySessionKeyName, "sticky-session-key-name", "", "Name of the cookie or header holding the session key with the sticky strategy")
+	flag.StringVar(&options.StickySessionAffinityCookieName, "sticky-session-affinity-cookie-name", "", "Name of a cookie pinning a session to the target that served it (empty to disable)")
+	flag.StringVar(&options.StickySessionAffinityCookieMaxAge, "sticky-session-affinity-cookie-max-age", "0", "Max age of the affinity cookie (0 for a session cookie)")
+	flag.StringVar(&options.CanaryByHeader, "canary-by-header", "", "Header forcing requests to the canary target when set to always, or to the primary when set to never")
+	flag.StringVar(&options.CanaryByHeaderValue, "canary-by-header-value", "", "Value of the canary header forcing requests to the canary target, instead of always")
+	flag.StringVar(&options.CanaryByHeaderPattern, "canary-by-header-pattern", "", "Regular expression the canary header is matched against, instead of always")
+	flag.StringVar(&options.CanaryByCookie, "canary-by-cookie", "", "Cookie forcing requests to the canary target when set to always, or to the primary when set to never")
+	flag.BoolVar(&options.EnableHTTP2, "enable-http2", false, "Accept HTTP/2 over cleartext (h2c) and proxy gRPC requests to targets over HTTP/2")
+	flag.StringVar(&options.TCPProxyListeners, "tcp-proxy-listeners", "", "Comma delimited raw TCP listeners, each as <listen address>=<resource name>:<target port>")
+	flag.StringVar(&options.TCPProxyDialTimeout, "tcp-proxy-dial-timeout", "30s", "Maximal wait time for a TCP proxy connection to a resource")
+	flag.StringVar(&options.TLSCertFile, "tls-cert-file", "", "Path of a certificate file to serve TLS with (empty to serve plain HTTP)")
+	flag.StringVar(&options.TLSKeyFile, "tls-key-file", "", "Path of the key file of the TLS certificate")
+	flag.BoolVar(&options.TLSIngressSecrets, "tls-ingress-secrets", false, "Serve TLS with the secrets referenced by the spec.tls of the watched ingresses, selected by SNI host")
+	flag.StringVar(&options.TLSReloadInterval, "tls-reload-interval", "10s", "How often the TLS certificate files are checked for changes")
+	flag.StringVar(&options.ShutdownTimeout, "shutdown-timeout", "25s", "Maximal wait time for in-flight requests on shutdown, after which they are aborted (keep below the pod's termination grace period)")
+	flag.StringVar(&options.ServerReadTimeout, "server-read-timeout", "0", "Maximal time to read a whole request, including its body (0 for unbounded)")
+	flag.StringVar(&options.ServerReadHeaderTimeout, "server-read-header-timeout", "10s", "Maximal time to read the headers of a request (0 for unbounded)")
+	flag.StringVar(&options.ServerWriteTimeout, "server-write-timeout", "0", "Maximal time to handle a request, including waiting for its resource to wake up (0 for unbounded)")
+	flag.StringVar(&options.ServerIdleTimeout, "server-idle-timeout", "2m", "How long an idle keep-alive connection is kept open (0 for unbounded)")
+	flag.IntVar(&options.ServerMaxHeaderBytes, "server-max-header-bytes", 0, "Maximal size of the headers of a request (0 for 1MB)")
+	flag.BoolVar(&options.ServerDisableKeepAlives, "server-disable-keep-alives", false, "Close every connection after serving a single request")
+	flag.StringVar(&options.ErrorPageTemplateFile, "error-page-template-file", "", "Path of an html/template file of the page shown to browsers while a resource wakes up (empty for the built-in page)")
+	flag.StringVar(&options.ErrorPageHostTemplateFiles, "error-page-host-template-files", "", "Comma delimited error page templates of specific hosts, each as <host>=<template file path>")
+	flag.StringVar(&options.ErrorPageRefreshInterval, "error-page-refresh-interval", "5s", "How often the error page is refreshed, unless the response has a Retry-After")
+	flag.StringVar(&options.ErrorPageWakeGracePeriod, "error-page-wake-grace-period", "0s", "How long page loads of browsers wait for their resources before they are shown the page of a resource waking up (0 to wait for the wake-up)")
+	flag.StringVar(&options.AsyncWakeRoutes, "async-wake-routes", "", "Comma delimited routes answered as soon as they trigger a wake-up, each as <host><path prefix>[=<status>[:queue]] (empty host for any host). requires the readiness cache")
+	flag.StringVar(&options.AsyncWakeQueueDir, "async-wake-queue-dir", "", "Directory the requests of queueing async routes are stored in until replayed")
+	flag.Int64Var(&options.AsyncWakeMaxQueuedBodySize, "async-wake-max-queued-body-size", 10*1024*1024, "Maximal size of a request body queued by an async route")
+	flag.StringVar(&options.ControlAPITokenFile, "control-api-token-file", "", "Path of a file holding the bearer token of the control API under /_scaler/ (empty to disable the API)")
 	flag.Parse()
 
-	*namespace = common.GetNamespace(*namespace)
+	options.Namespace = common.GetNamespace(options.Namespace)
 
-	if err := app.Run(*kubeconfigPath,
-		*namespace,
-		*targetNameHeader,
-		*targetPathHeader,
-		*targetPort,
-		*listenAddress,
-		*resourceReadinessTimeout,
-		*multiTargetStrategy); err != nil {
+	if err := app.Run(options); err != nil {
 		errors.PrintErrorStack(os.Stderr, err, 5)
 
 		os.Exit(1)
@@ -21,6 +21,9 @@ such restriction.
 package autoscaler
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/v3io/scaler/pkg/common"
@@ -28,21 +31,33 @@ import (
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/metrics/pkg/client/custom_metrics"
 )
 
 type Autoscaler struct {
-	logger                  logger.Logger
-	namespace               string
-	resourceScaler          scalertypes.ResourceScaler
-	scaleInterval           scalertypes.Duration
-	inScaleToZeroProcessMap map[string]bool
-	groupKind               schema.GroupKind
-	customMetricsClientSet  custom_metrics.CustomMetricsClient
-	ticker                  *time.Ticker
+	logger                         logger.Logger
+	namespace                      string
+	resourceScaler                 scalertypes.ResourceScaler
+	scaleInterval                  scalertypes.Duration
+	inScaleToZeroProcessMap        map[string]bool
+	groupKind                      schema.GroupKind
+	customMetricsClientSet         custom_metrics.CustomMetricsClient
+	kubeClientSet                  kubernetes.Interface
+	stopChan                       chan struct{}
+	stoppedChan                    chan struct{}
+	stuckScaleEventThreshold       time.Duration
+	stuckScaleEventAction          scalertypes.StuckScaleEventAction
+	stuckResourcesReconcileTimeMap map[string]time.Time
+	warmResourcesBudget            scalertypes.WarmResourcesBudget
+
+	// the scalings started by the checks, which Stop waits for
+	scalingsWaitGroup sync.WaitGroup
 }
 
 func NewAutoScaler(parentLogger logger.Logger,
@@ -53,38 +68,68 @@ func NewAutoScaler(parentLogger logger.Logger,
 	childLogger.InfoWith("Creating Autoscaler",
 		"options", options)
 
+	stuckScaleEventAction := options.StuckScaleEventAction
+	switch stuckScaleEventAction {
+	case "":
+		stuckScaleEventAction = scalertypes.StuckScaleEventActionRetryAndAlert
+	case scalertypes.StuckScaleEventActionRetry,
+		scalertypes.StuckScaleEventActionAlert,
+		scalertypes.StuckScaleEventActionRetryAndAlert:
+	default:
+		return nil, errors.Errorf("Unknown stuck scale event action: %s", stuckScaleEventAction)
+	}
+
 	return &Autoscaler{
-		logger:                  childLogger,
-		namespace:               options.Namespace,
-		resourceScaler:          resourceScaler,
-		scaleInterval:           options.ScaleInterval,
-		groupKind:               options.GroupKind,
-		customMetricsClientSet:  customMetricsClientSet,
-		inScaleToZeroProcessMap: make(map[string]bool),
+		logger:                         childLogger,
+		namespace:                      options.Namespace,
+		resourceScaler:                 resourceScaler,
+		scaleInterval:                  options.ScaleInterval,
+		groupKind:                      options.GroupKind,
+		customMetricsClientSet:         customMetricsClientSet,
+		kubeClientSet:                  options.KubeClientSet,
+		inScaleToZeroProcessMap:        make(map[string]bool),
+		stuckScaleEventThreshold:       options.StuckScaleEventThreshold.Duration,
+		stuckScaleEventAction:          stuckScaleEventAction,
+		stuckResourcesReconcileTimeMap: make(map[string]time.Time),
+		warmResourcesBudget:            options.WarmResourcesBudget,
 	}, nil
 }
 
 func (as *Autoscaler) Start() error {
 	as.logger.DebugWith("Starting", "scaleInterval", as.scaleInterval)
-	as.ticker = time.NewTicker(as.scaleInterval.Duration)
+	as.stopChan = make(chan struct{})
+	as.stoppedChan = make(chan struct{})
+	ticker := time.NewTicker(as.scaleInterval.Duration)
 	go func() {
-		for range as.ticker.C {
-			if err := as.checkResourcesToScale(); err != nil {
-				as.logger.WarnWith("Failed to check resources to scale",
-					"err", errors.GetErrorStackString(err, 10))
+		defer close(as.stoppedChan)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-as.stopChan:
+				as.logger.Debug("Stopped ticking")
+				return
+			case <-ticker.C:
+				if err := as.checkResourcesToScale(); err != nil {
+					as.logger.WarnWith("Failed to check resources to scale",
+						"err", errors.GetErrorStackString(err, 10))
+				}
 			}
 		}
-		as.logger.Debug("Stopped ticking")
-		as.ticker = nil
 	}()
 	return nil
 }
 
+// Stop stops checking the resources to scale, and waits for a check in progress and the scalings it started to finish
+// so that no resource is left mid scale
 func (as *Autoscaler) Stop() error {
-	if as.ticker != nil {
+	if as.stopChan != nil {
 		as.logger.Debug("Stopping")
-		as.ticker.Stop()
+		close(as.stopChan)
+		<-as.stoppedChan
+		as.stopChan = nil
 	}
+	as.scalingsWaitGroup.Wait()
 	return nil
 }
 
@@ -196,6 +241,10 @@ func (as *Autoscaler) checkResourcesToScale() error {
 	if len(activeResources) == 0 {
 		return nil
 	}
+
+	// stuck resources are handled by the reconciler and are not scale-to-zero candidates on this round
+	stuckResourceNames := as.reconcileStuckResources(activeResources, now)
+
 	metricNames := as.getMetricNames(activeResources)
 	as.logger.DebugWith("Got metric names", "metricNames", metricNames)
 	resourceMetricsMap, err := as.getResourceMetrics(metricNames)
@@ -204,6 +253,7 @@ func (as *Autoscaler) checkResourcesToScale() error {
 	}
 
 	resourcesToScale := make([]scalertypes.Resource, 0)
+	evictionCandidates := make([]scalertypes.Resource, 0)
 	for idx, resource := range activeResources {
 		inScaleToZeroProcess, found := as.inScaleToZeroProcessMap[resource.Name]
 		if found && inScaleToZeroProcess {
@@ -212,6 +262,10 @@ func (as *Autoscaler) checkResourcesToScale() error {
 			continue
 		}
 
+		if _, stuck := stuckResourceNames[resource.Name]; stuck {
+			continue
+		}
+
 		scaleEventDebounceDuration := as.getMaxScaleResourceWindowSize(resource)
 
 		// if the resource was scaled from zero or updated, and the debounce period from then has not passed yet do not scale
@@ -229,18 +283,32 @@ func (as *Autoscaler) checkResourcesToScale() error {
 			continue
 		}
 
+		// regardless of its thresholds, the resource may be scaled to zero to keep within the warm resources budget
+		evictionCandidates = append(evictionCandidates, activeResources[idx])
+
 		shouldScaleToZero := as.checkResourceToScale(resource, resourceMetricsMap)
 
 		if !shouldScaleToZero {
 			continue
 		}
 
-		as.inScaleToZeroProcessMap[resource.Name] = true
 		resourcesToScale = append(resourcesToScale, activeResources[idx])
 	}
 
-	if len(resourcesToScale) > 0 {
+	resourcesBatches := as.groupResourcesToScale(activeResources, resourcesToScale)
+	resourcesBatches = append(resourcesBatches, as.getBudgetEvictionBatches(activeResources,
+		evictionCandidates,
+		resourcesBatches,
+		resourceMetricsMap)...)
+
+	for _, resourcesBatch := range resourcesBatches {
+		for _, resource := range resourcesBatch {
+			as.inScaleToZeroProcessMap[resource.Name] = true
+		}
+
+		as.scalingsWaitGroup.Add(1)
 		go func(resources []scalertypes.Resource) {
+			defer as.scalingsWaitGroup.Done()
 			as.logger.InfoWith("Scaling resources to zero", "resources", resources)
 			if err := as.scaleResourcesToZero(resources); err != nil {
 				as.logger.WarnWith("Failed to scale resources to zero",
@@ -251,12 +319,77 @@ func (as *Autoscaler) checkResourcesToScale() error {
 			for _, resource := range resources {
 				delete(as.inScaleToZeroProcessMap, resource.Name)
 			}
-		}(resourcesToScale)
+		}(resourcesBatch)
 	}
 
 	return nil
 }
 
+// groupResourcesToScale splits the scale-to-zero candidates into batches, each scaled with a single SetScale call.
+// ungrouped candidates are batched together, while a scale group is batched only when all of its members are idle
+func (as *Autoscaler) groupResourcesToScale(activeResources []scalertypes.Resource,
+	candidates []scalertypes.Resource) [][]scalertypes.Resource {
+	candidateNames := map[string]struct{}{}
+	for _, candidate := range candidates {
+		candidateNames[candidate.Name] = struct{}{}
+	}
+
+	var ungroupedResources []scalertypes.Resource
+	var scaleGroupKeys []string
+	groupedResources := map[string][]scalertypes.Resource{}
+	for _, candidate := range candidates {
+		if candidate.ScaleGroup == "" {
+			ungroupedResources = append(ungroupedResources, candidate)
+			continue
+		}
+
+		scaleGroupKey := as.getScaleGroupKey(candidate)
+		if _, found := groupedResources[scaleGroupKey]; !found {
+			scaleGroupKeys = append(scaleGroupKeys, scaleGroupKey)
+		}
+		groupedResources[scaleGroupKey] = append(groupedResources[scaleGroupKey], candidate)
+	}
+
+	var resourcesBatches [][]scalertypes.Resource
+	if len(ungroupedResources) > 0 {
+		resourcesBatches = append(resourcesBatches, ungroupedResources)
+	}
+
+	for _, scaleGroupKey := range scaleGroupKeys {
+		if as.isScaleGroupIdle(scaleGroupKey, activeResources, candidateNames) {
+			resourcesBatches = append(resourcesBatches, groupedResources[scaleGroupKey])
+		}
+	}
+
+	return resourcesBatches
+}
+
+// isScaleGroupIdle returns true if every member of the scale group which is not already scaled to zero is a candidate
+func (as *Autoscaler) isScaleGroupIdle(scaleGroupKey string,
+	activeResources []scalertypes.Resource,
+	candidateNames map[string]struct{}) bool {
+	for _, resource := range activeResources {
+		if resource.ScaleGroup == "" ||
+			as.getScaleGroupKey(resource) != scaleGroupKey ||
+			resource.IsScaledToZero() {
+			continue
+		}
+
+		if _, isCandidate := candidateNames[resource.Name]; !isCandidate {
+			as.logger.DebugWith("Scale group member is not idle, keeping group up",
+				"scaleGroup", resource.ScaleGroup,
+				"resourceName", resource.Name)
+			return false
+		}
+	}
+
+	return true
+}
+
+func (as *Autoscaler) getScaleGroupKey(resource scalertypes.Resource) string {
+	return fmt.Sprintf("%s/%s", resource.Namespace, resource.ScaleGroup)
+}
+
 func (as *Autoscaler) scaleResourcesToZero(resources []scalertypes.Resource) error {
 	if err := as.resourceScaler.SetScale(resources, 0); err != nil {
 		return errors.Wrap(err, "Failed to set scale")
@@ -264,3 +397,133 @@ func (as *Autoscaler) scaleResourcesToZero(resources []scalertypes.Resource) err
 
 	return nil
 }
+
+// reconcileStuckResources detects resources that started a scale transition and did not complete it within the
+// stuck threshold, retries the transition and / or alerts on them. returns the names of the stuck resources
+func (as *Autoscaler) reconcileStuckResources(resources []scalertypes.Resource, now time.Time) map[string]struct{} {
+	stuckResourceNames := map[string]struct{}{}
+	if as.stuckScaleEventThreshold == 0 {
+		return stuckResourceNames
+	}
+
+	for _, resource := range resources {
+		if !as.isStuckInScaleTransition(resource, now) {
+			continue
+		}
+		stuckResourceNames[resource.Name] = struct{}{}
+
+		// reconcile each stuck resource at most once per threshold period, giving the previous attempt time to converge
+		if lastReconcileTime, found := as.stuckResourcesReconcileTimeMap[resource.Name]; found &&
+			lastReconcileTime.After(now.Add(-1*as.stuckScaleEventThreshold)) {
+			continue
+		}
+		as.stuckResourcesReconcileTimeMap[resource.Name] = now
+
+		as.logger.WarnWith("Resource is stuck in scale transition",
+			"resourceName", resource.Name,
+			"lastScaleEvent", *resource.LastScaleEvent,
+			"lastScaleEventTime", *resource.LastScaleEventTime,
+			"threshold", as.stuckScaleEventThreshold,
+			"action", as.stuckScaleEventAction)
+
+		if as.stuckScaleEventAction.ShouldAlert() {
+			if err := as.emitStuckResourceEvent(resource); err != nil {
+				as.logger.WarnWith("Failed to emit stuck resource event",
+					"resourceName", resource.Name,
+					"err", errors.GetErrorStackString(err, 10))
+			}
+		}
+
+		if as.stuckScaleEventAction.ShouldRetry() {
+			as.scalingsWaitGroup.Add(1)
+			go func(resource scalertypes.Resource) {
+				defer as.scalingsWaitGroup.Done()
+				as.retryScaleTransition(resource)
+			}(resource)
+		}
+	}
+
+	// forget resources that are no longer stuck
+	for resourceName := range as.stuckResourcesReconcileTimeMap {
+		if _, stuck := stuckResourceNames[resourceName]; !stuck {
+			delete(as.stuckResourcesReconcileTimeMap, resourceName)
+		}
+	}
+
+	return stuckResourceNames
+}
+
+func (as *Autoscaler) isStuckInScaleTransition(resource scalertypes.Resource, now time.Time) bool {
+	if resource.LastScaleEvent == nil || resource.LastScaleEventTime == nil {
+		return false
+	}
+
+	if *resource.LastScaleEvent != scalertypes.ScaleToZeroStartedScaleEvent &&
+		*resource.LastScaleEvent != scalertypes.ScaleFromZeroStartedScaleEvent {
+		return false
+	}
+
+	return resource.LastScaleEventTime.Before(now.Add(-1 * as.stuckScaleEventThreshold))
+}
+
+func (as *Autoscaler) retryScaleTransition(resource scalertypes.Resource) {
+	scale := 0
+	if *resource.LastScaleEvent == scalertypes.ScaleFromZeroStartedScaleEvent {
+		scale = resource.GetWakeReplicas()
+	}
+
+	as.logger.InfoWith("Retrying stuck scale transition",
+		"resourceName", resource.Name,
+		"lastScaleEvent", *resource.LastScaleEvent,
+		"scale", scale)
+	if err := as.resourceScaler.SetScale([]scalertypes.Resource{resource}, scale); err != nil {
+		as.logger.WarnWith("Failed to retry stuck scale transition",
+			"resourceName", resource.Name,
+			"scale", scale,
+			"err", errors.GetErrorStackString(err, 10))
+		return
+	}
+
+	as.logger.InfoWith("Successfully retried stuck scale transition",
+		"resourceName", resource.Name,
+		"scale", scale)
+}
+
+func (as *Autoscaler) emitStuckResourceEvent(resource scalertypes.Resource) error {
+	if as.kubeClientSet == nil {
+		return nil
+	}
+
+	namespace := resource.Namespace
+	if namespace == "" {
+		namespace = as.namespace
+	}
+
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", resource.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:      as.groupKind.Kind,
+			Name:      resource.Name,
+			Namespace: namespace,
+		},
+		Reason: "ScaleTransitionStuck",
+		Message: fmt.Sprintf("Resource has been in %s since %s",
+			*resource.LastScaleEvent,
+			resource.LastScaleEventTime.Format(time.RFC3339)),
+		Type:           v1.EventTypeWarning,
+		Source:         v1.EventSource{Component: "autoscaler"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	if _, err := as.kubeClientSet.CoreV1().Events(namespace).Create(context.Background(), event, metav1.CreateOptions{}); err != nil {
+		return errors.Wrap(err, "Failed to create event")
+	}
+
+	return nil
+}
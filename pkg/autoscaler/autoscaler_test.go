@@ -0,0 +1,464 @@
+/*
+Copyright 2019 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package autoscaler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mockresourcescaler "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type AutoscalerTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	scaler        *mockresourcescaler.ResourceScaler
+	kubeClientSet *fake.Clientset
+}
+
+func (suite *AutoscalerTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *AutoscalerTestSuite) SetupTest() {
+	suite.scaler = &mockresourcescaler.ResourceScaler{}
+	suite.kubeClientSet = fake.NewSimpleClientset()
+}
+
+func (suite *AutoscalerTestSuite) TestReconcileStuckResources() {
+	now := time.Now()
+	for _, testCase := range []struct {
+		name                  string
+		action                scalertypes.StuckScaleEventAction
+		lastScaleEvent        scalertypes.ScaleEvent
+		lastScaleEventTime    time.Time
+		wakeReplicas          int
+		expectStuck           bool
+		expectedScale         int
+		expectedEventsCreated int
+	}{
+		{
+			name:                  "Stuck scaling to zero, retry and alert",
+			action:                scalertypes.StuckScaleEventActionRetryAndAlert,
+			lastScaleEvent:        scalertypes.ScaleToZeroStartedScaleEvent,
+			lastScaleEventTime:    now.Add(-10 * time.Minute),
+			expectStuck:           true,
+			expectedScale:         0,
+			expectedEventsCreated: 1,
+		},
+		{
+			name:               "Stuck scaling from zero, retry only",
+			action:             scalertypes.StuckScaleEventActionRetry,
+			lastScaleEvent:     scalertypes.ScaleFromZeroStartedScaleEvent,
+			lastScaleEventTime: now.Add(-10 * time.Minute),
+			expectStuck:        true,
+			expectedScale:      1,
+		},
+		{
+			name:               "Stuck scaling from zero, retry with wake replicas",
+			action:             scalertypes.StuckScaleEventActionRetry,
+			lastScaleEvent:     scalertypes.ScaleFromZeroStartedScaleEvent,
+			lastScaleEventTime: now.Add(-10 * time.Minute),
+			wakeReplicas:       3,
+			expectStuck:        true,
+			expectedScale:      3,
+		},
+		{
+			name:                  "Stuck scaling from zero, alert only",
+			action:                scalertypes.StuckScaleEventActionAlert,
+			lastScaleEvent:        scalertypes.ScaleFromZeroStartedScaleEvent,
+			lastScaleEventTime:    now.Add(-10 * time.Minute),
+			expectStuck:           true,
+			expectedEventsCreated: 1,
+		},
+		{
+			name:               "Started transition within threshold is not stuck",
+			action:             scalertypes.StuckScaleEventActionRetryAndAlert,
+			lastScaleEvent:     scalertypes.ScaleToZeroStartedScaleEvent,
+			lastScaleEventTime: now.Add(-1 * time.Minute),
+		},
+		{
+			name:               "Completed transition is not stuck",
+			action:             scalertypes.StuckScaleEventActionRetryAndAlert,
+			lastScaleEvent:     scalertypes.ScaleToZeroCompletedScaleEvent,
+			lastScaleEventTime: now.Add(-10 * time.Minute),
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+			setScaleCalled := make(chan int, 1)
+			suite.scaler.
+				On("SetScale", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					setScaleCalled <- args.Int(1)
+				}).
+				Return(nil)
+
+			testAutoscaler := suite.createTestAutoscaler(testCase.action)
+			resource := scalertypes.Resource{
+				Name:               "test-resource",
+				Namespace:          "default",
+				LastScaleEvent:     &testCase.lastScaleEvent,
+				LastScaleEventTime: &testCase.lastScaleEventTime,
+				WakeReplicas:       testCase.wakeReplicas,
+			}
+
+			stuckResourceNames := testAutoscaler.reconcileStuckResources([]scalertypes.Resource{resource}, now)
+			_, stuck := stuckResourceNames[resource.Name]
+			suite.Require().Equal(testCase.expectStuck, stuck)
+
+			if testCase.expectStuck && testCase.action.ShouldRetry() {
+				select {
+				case scale := <-setScaleCalled:
+					suite.Require().Equal(testCase.expectedScale, scale)
+				case <-time.After(time.Second):
+					suite.Fail("Timed out waiting for the stuck transition to be retried")
+				}
+			} else {
+				suite.scaler.AssertNotCalled(suite.T(), "SetScale", mock.Anything, mock.Anything)
+			}
+
+			events, err := suite.kubeClientSet.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+			suite.Require().NoError(err)
+			suite.Require().Len(events.Items, testCase.expectedEventsCreated)
+
+			// a second pass within the threshold must not reconcile the resource again
+			testAutoscaler.reconcileStuckResources([]scalertypes.Resource{resource}, now.Add(time.Second))
+			events, err = suite.kubeClientSet.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+			suite.Require().NoError(err)
+			suite.Require().Len(events.Items, testCase.expectedEventsCreated)
+		})
+	}
+}
+
+func (suite *AutoscalerTestSuite) TestUnknownStuckScaleEventAction() {
+	_, err := NewAutoScaler(suite.logger, suite.scaler, nil, scalertypes.AutoScalerOptions{
+		StuckScaleEventAction: "retryAndAlret",
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *AutoscalerTestSuite) TestGroupResourcesToScale() {
+	scaledToZeroEvent := scalertypes.ScaleToZeroCompletedScaleEvent
+	for _, testCase := range []struct {
+		name            string
+		activeResources []scalertypes.Resource
+		candidateNames  []string
+		expectedBatches [][]string
+	}{
+		{
+			name: "Ungrouped candidates are scaled together",
+			activeResources: []scalertypes.Resource{
+				{Name: "a"},
+				{Name: "b"},
+				{Name: "c"},
+			},
+			candidateNames:  []string{"a", "c"},
+			expectedBatches: [][]string{{"a", "c"}},
+		},
+		{
+			name: "Group is scaled once all members are idle",
+			activeResources: []scalertypes.Resource{
+				{Name: "a"},
+				{Name: "primary", ScaleGroup: "pair"},
+				{Name: "canary", ScaleGroup: "pair"},
+			},
+			candidateNames:  []string{"a", "primary", "canary"},
+			expectedBatches: [][]string{{"a"}, {"primary", "canary"}},
+		},
+		{
+			name: "Group is kept up while one member is busy",
+			activeResources: []scalertypes.Resource{
+				{Name: "a"},
+				{Name: "primary", ScaleGroup: "pair"},
+				{Name: "canary", ScaleGroup: "pair"},
+			},
+			candidateNames:  []string{"a", "primary"},
+			expectedBatches: [][]string{{"a"}},
+		},
+		{
+			name: "Members already scaled to zero do not keep the group up",
+			activeResources: []scalertypes.Resource{
+				{Name: "primary", ScaleGroup: "pair"},
+				{Name: "canary", ScaleGroup: "pair", LastScaleEvent: &scaledToZeroEvent},
+			},
+			candidateNames:  []string{"primary"},
+			expectedBatches: [][]string{{"primary"}},
+		},
+		{
+			name: "Same group name in different namespaces are different groups",
+			activeResources: []scalertypes.Resource{
+				{Name: "a", Namespace: "ns1", ScaleGroup: "group"},
+				{Name: "b", Namespace: "ns2", ScaleGroup: "group"},
+			},
+			candidateNames:  []string{"a"},
+			expectedBatches: [][]string{{"a"}},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testAutoscaler := suite.createTestAutoscaler(scalertypes.StuckScaleEventActionAlert)
+
+			var candidates []scalertypes.Resource
+			for _, candidateName := range testCase.candidateNames {
+				for _, resource := range testCase.activeResources {
+					if resource.Name == candidateName {
+						candidates = append(candidates, resource)
+					}
+				}
+			}
+
+			var batchesNames [][]string
+			for _, batch := range testAutoscaler.groupResourcesToScale(testCase.activeResources, candidates) {
+				var batchNames []string
+				for _, resource := range batch {
+					batchNames = append(batchNames, resource.Name)
+				}
+				batchesNames = append(batchesNames, batchNames)
+			}
+			suite.Require().Equal(testCase.expectedBatches, batchesNames)
+		})
+	}
+}
+
+func (suite *AutoscalerTestSuite) TestGetBudgetEvictionBatches() {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour)
+	minuteAgo := now.Add(-time.Minute)
+	scaledToZeroEvent := scalertypes.ScaleToZeroCompletedScaleEvent
+	scaleResources := []scalertypes.ScaleResource{{MetricName: "requests", WindowSize: scalertypes.Duration{Duration: time.Minute}}}
+	metricName := scaleResources[0].GetKubernetesMetricName()
+
+	for _, testCase := range []struct {
+		name            string
+		budget          scalertypes.WarmResourcesBudget
+		activeResources []scalertypes.Resource
+		candidateNames  []string
+		scheduledNames  []string
+		metrics         map[string]int
+		expectedEvicted [][]string
+	}{
+		{
+			name:   "Within budget",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 2},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &hourAgo},
+				{Name: "b", LastScaleEventTime: &minuteAgo},
+			},
+			candidateNames: []string{"a", "b"},
+		},
+		{
+			name:   "Least recently used is evicted first",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &minuteAgo},
+				{Name: "b", LastScaleEventTime: &hourAgo},
+			},
+			candidateNames:  []string{"a", "b"},
+			expectedEvicted: [][]string{{"b"}},
+		},
+		{
+			name:   "Idle resources are evicted before least recently used",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &minuteAgo, ScaleResources: scaleResources},
+				{Name: "b", LastScaleEventTime: &hourAgo, ScaleResources: scaleResources},
+			},
+			candidateNames:  []string{"a", "b"},
+			metrics:         map[string]int{"a": 0, "b": 1000},
+			expectedEvicted: [][]string{{"a"}},
+		},
+		{
+			name:   "Cost is accounted",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 3},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &hourAgo},
+				{Name: "b", LastScaleEventTime: &minuteAgo, Cost: 3},
+			},
+			candidateNames:  []string{"a", "b"},
+			expectedEvicted: [][]string{{"a"}},
+		},
+		{
+			name:   "Scaled to zero and scheduled resources are not accounted",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &hourAgo},
+				{Name: "b", LastScaleEventTime: &hourAgo, LastScaleEvent: &scaledToZeroEvent},
+				{Name: "c", LastScaleEventTime: &hourAgo},
+			},
+			candidateNames: []string{"a", "b"},
+			scheduledNames: []string{"c"},
+		},
+		{
+			name:   "Non candidates are accounted but not evicted",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &minuteAgo},
+				{Name: "b", LastScaleEventTime: &hourAgo},
+			},
+			candidateNames:  []string{"a"},
+			expectedEvicted: [][]string{{"a"}},
+		},
+		{
+			name:   "Scale group is evicted as a unit",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 2},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", LastScaleEventTime: &minuteAgo},
+				{Name: "primary", ScaleGroup: "pair", LastScaleEventTime: &hourAgo},
+				{Name: "canary", ScaleGroup: "pair", LastScaleEventTime: &hourAgo},
+			},
+			candidateNames:  []string{"a", "primary", "canary"},
+			expectedEvicted: [][]string{{"primary", "canary"}},
+		},
+		{
+			name:   "Budget per namespace",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1, Scope: scalertypes.WarmResourcesBudgetScopeNamespace},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", Namespace: "ns1", LastScaleEventTime: &hourAgo},
+				{Name: "b", Namespace: "ns2", LastScaleEventTime: &hourAgo},
+			},
+			candidateNames: []string{"a", "b"},
+		},
+		{
+			name:   "Global budget",
+			budget: scalertypes.WarmResourcesBudget{MaxWarmResources: 1, Scope: scalertypes.WarmResourcesBudgetScopeGlobal},
+			activeResources: []scalertypes.Resource{
+				{Name: "a", Namespace: "ns1", LastScaleEventTime: &hourAgo},
+				{Name: "b", Namespace: "ns2", LastScaleEventTime: &minuteAgo},
+			},
+			candidateNames:  []string{"a", "b"},
+			expectedEvicted: [][]string{{"a"}},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testAutoscaler := suite.createTestAutoscaler(scalertypes.StuckScaleEventActionAlert)
+			testAutoscaler.warmResourcesBudget = testCase.budget
+
+			var candidates, scheduled []scalertypes.Resource
+			for _, resource := range testCase.activeResources {
+				for _, candidateName := range testCase.candidateNames {
+					if resource.Name == candidateName {
+						candidates = append(candidates, resource)
+					}
+				}
+				for _, scheduledName := range testCase.scheduledNames {
+					if resource.Name == scheduledName {
+						scheduled = append(scheduled, resource)
+					}
+				}
+			}
+
+			resourcesMetricsMap := map[string]map[string]int{}
+			for resourceName, value := range testCase.metrics {
+				resourcesMetricsMap[resourceName] = map[string]int{metricName: value}
+			}
+
+			var evictedNames [][]string
+			for _, batch := range testAutoscaler.getBudgetEvictionBatches(testCase.activeResources,
+				candidates,
+				[][]scalertypes.Resource{scheduled},
+				resourcesMetricsMap) {
+				var batchNames []string
+				for _, resource := range batch {
+					batchNames = append(batchNames, resource.Name)
+				}
+				evictedNames = append(evictedNames, batchNames)
+			}
+			suite.Require().Equal(testCase.expectedEvicted, evictedNames)
+		})
+	}
+}
+
+func (suite *AutoscalerTestSuite) TestStop() {
+	var checks atomic.Int32
+	suite.scaler.On("GetResources").
+		Run(func(args mock.Arguments) { checks.Add(1) }).
+		Return([]scalertypes.Resource{}, nil)
+
+	testAutoscaler := suite.createTestAutoscaler(scalertypes.StuckScaleEventActionRetryAndAlert)
+	testAutoscaler.scaleInterval = scalertypes.Duration{Duration: 10 * time.Millisecond}
+	suite.Require().NoError(testAutoscaler.Start())
+
+	suite.Require().Eventually(func() bool {
+		return checks.Load() > 0
+	}, 3*time.Second, 10*time.Millisecond)
+	suite.Require().NoError(testAutoscaler.Stop())
+
+	// no resources are checked once stopped
+	checksOnStop := checks.Load()
+	time.Sleep(100 * time.Millisecond)
+	suite.Require().Equal(checksOnStop, checks.Load())
+}
+
+func (suite *AutoscalerTestSuite) TestStopWaitsForScalings() {
+	var scaled atomic.Bool
+	suite.scaler.On("SetScale", mock.Anything, mock.Anything).
+		After(300 * time.Millisecond).
+		Run(func(args mock.Arguments) { scaled.Store(true) }).
+		Return(nil)
+
+	testAutoscaler := suite.createTestAutoscaler(scalertypes.StuckScaleEventActionRetry)
+	lastScaleEvent := scalertypes.ScaleToZeroStartedScaleEvent
+	lastScaleEventTime := time.Now().Add(-10 * time.Minute)
+	testAutoscaler.reconcileStuckResources([]scalertypes.Resource{
+		{
+			Name:               "test-resource",
+			Namespace:          "default",
+			LastScaleEvent:     &lastScaleEvent,
+			LastScaleEventTime: &lastScaleEventTime,
+		},
+	}, time.Now())
+
+	// the retry of the stuck scale transition is not left mid scale
+	suite.Require().NoError(testAutoscaler.Stop())
+	suite.Require().True(scaled.Load())
+}
+
+// --- AutoscalerTestSuite suite methods ---
+
+func (suite *AutoscalerTestSuite) createTestAutoscaler(action scalertypes.StuckScaleEventAction) *Autoscaler {
+	testAutoscaler, err := NewAutoScaler(suite.logger, suite.scaler, nil, scalertypes.AutoScalerOptions{
+		Namespace:                "default",
+		ScaleInterval:            scalertypes.Duration{Duration: time.Minute},
+		GroupKind:                schema.GroupKind{Kind: "TestResource", Group: "test.io"},
+		StuckScaleEventThreshold: scalertypes.Duration{Duration: 5 * time.Minute},
+		StuckScaleEventAction:    action,
+		KubeClientSet:            suite.kubeClientSet,
+	})
+	suite.Require().NoError(err)
+	return testAutoscaler
+}
+
+func TestAutoscalerTestSuite(t *testing.T) {
+	suite.Run(t, new(AutoscalerTestSuite))
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package autoscaler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+)
+
+// evictionUnit is a resource, or a whole scale group, that can be scaled to zero to keep within the budget
+type evictionUnit struct {
+	resources    []scalertypes.Resource
+	scopeKey     string
+	cost         int
+	idle         bool
+	lastUsedTime time.Time
+}
+
+// getBudgetEvictionBatches returns batches of resources to scale to zero so that the cost of the warm resources in each
+// budget scope does not exceed the budget. idle resources are evicted first, then the least recently used ones, where
+// the last scale event time (i.e. when the resource was last woken up or updated) is used as the usage time
+func (as *Autoscaler) getBudgetEvictionBatches(activeResources []scalertypes.Resource,
+	evictionCandidates []scalertypes.Resource,
+	scheduledBatches [][]scalertypes.Resource,
+	resourcesMetricsMap map[string]map[string]int) [][]scalertypes.Resource {
+	if !as.warmResourcesBudget.Enabled() {
+		return nil
+	}
+
+	// resources already on their way to zero are not accounted in the budget
+	scheduledResourceNames := map[string]struct{}{}
+	for _, resourcesBatch := range scheduledBatches {
+		for _, resource := range resourcesBatch {
+			scheduledResourceNames[resource.Name] = struct{}{}
+		}
+	}
+	for resourceName, inScaleToZeroProcess := range as.inScaleToZeroProcessMap {
+		if inScaleToZeroProcess {
+			scheduledResourceNames[resourceName] = struct{}{}
+		}
+	}
+
+	var warmResources []scalertypes.Resource
+	for _, resource := range activeResources {
+		if _, scheduled := scheduledResourceNames[resource.Name]; !scheduled {
+			warmResources = append(warmResources, resource)
+		}
+	}
+
+	warmCosts := map[string]int{}
+	for _, resource := range warmResources {
+		if resource.IsWarm() {
+			warmCosts[as.warmResourcesBudget.GetScopeKey(resource)] += resource.GetCost()
+		}
+	}
+
+	evictionUnits := as.getEvictionUnits(warmResources, evictionCandidates, resourcesMetricsMap)
+	sort.SliceStable(evictionUnits, func(i, j int) bool {
+		if evictionUnits[i].idle != evictionUnits[j].idle {
+			return evictionUnits[i].idle
+		}
+		return evictionUnits[i].lastUsedTime.Before(evictionUnits[j].lastUsedTime)
+	})
+
+	var evictionBatches [][]scalertypes.Resource
+	for _, unit := range evictionUnits {
+		if warmCosts[unit.scopeKey] <= as.warmResourcesBudget.MaxWarmResources {
+			continue
+		}
+
+		as.logger.InfoWith("Warm resources budget exceeded, scaling least recently used resources to zero",
+			"scope", unit.scopeKey,
+			"warmCost", warmCosts[unit.scopeKey],
+			"maxWarmResources", as.warmResourcesBudget.MaxWarmResources,
+			"resources", unit.resources)
+		evictionBatches = append(evictionBatches, unit.resources)
+		warmCosts[unit.scopeKey] -= unit.cost
+	}
+
+	for scopeKey, warmCost := range warmCosts {
+		if warmCost > as.warmResourcesBudget.MaxWarmResources {
+			as.logger.WarnWith("Warm resources budget exceeded with no resource left to scale to zero",
+				"scope", scopeKey,
+				"warmCost", warmCost,
+				"maxWarmResources", as.warmResourcesBudget.MaxWarmResources)
+		}
+	}
+
+	return evictionBatches
+}
+
+// getEvictionUnits groups the warm eviction candidates into units. a scale group is a single unit, which is eligible
+// only if all of its warm members are eviction candidates
+func (as *Autoscaler) getEvictionUnits(warmResources []scalertypes.Resource,
+	evictionCandidates []scalertypes.Resource,
+	resourcesMetricsMap map[string]map[string]int) []*evictionUnit {
+	candidateNames := map[string]struct{}{}
+	for _, candidate := range evictionCandidates {
+		candidateNames[candidate.Name] = struct{}{}
+	}
+
+	var evictionUnits []*evictionUnit
+	scaleGroupUnits := map[string]*evictionUnit{}
+	ineligibleScaleGroups := map[string]struct{}{}
+	for _, resource := range warmResources {
+		if !resource.IsWarm() {
+			continue
+		}
+
+		_, isCandidate := candidateNames[resource.Name]
+		if resource.ScaleGroup == "" {
+			if isCandidate {
+				evictionUnits = append(evictionUnits, as.newEvictionUnit(resource, resourcesMetricsMap))
+			}
+			continue
+		}
+
+		scaleGroupKey := as.getScaleGroupKey(resource)
+		if !isCandidate {
+			ineligibleScaleGroups[scaleGroupKey] = struct{}{}
+			continue
+		}
+
+		unit, found := scaleGroupUnits[scaleGroupKey]
+		if !found {
+			scaleGroupUnits[scaleGroupKey] = as.newEvictionUnit(resource, resourcesMetricsMap)
+			continue
+		}
+
+		memberUnit := as.newEvictionUnit(resource, resourcesMetricsMap)
+		unit.resources = append(unit.resources, resource)
+		unit.cost += memberUnit.cost
+		unit.idle = unit.idle && memberUnit.idle
+		if memberUnit.lastUsedTime.After(unit.lastUsedTime) {
+			unit.lastUsedTime = memberUnit.lastUsedTime
+		}
+	}
+
+	for scaleGroupKey, unit := range scaleGroupUnits {
+		if _, ineligible := ineligibleScaleGroups[scaleGroupKey]; !ineligible {
+			evictionUnits = append(evictionUnits, unit)
+		}
+	}
+
+	return evictionUnits
+}
+
+func (as *Autoscaler) newEvictionUnit(resource scalertypes.Resource,
+	resourcesMetricsMap map[string]map[string]int) *evictionUnit {
+	unit := &evictionUnit{
+		resources: []scalertypes.Resource{resource},
+		scopeKey:  as.warmResourcesBudget.GetScopeKey(resource),
+		cost:      resource.GetCost(),
+		idle:      as.isResourceIdle(resource, resourcesMetricsMap),
+	}
+	if resource.LastScaleEventTime != nil {
+		unit.lastUsedTime = *resource.LastScaleEventTime
+	}
+	return unit
+}
+
+// isResourceIdle returns true if all the metrics of the resource have data, and all of them are zero
+func (as *Autoscaler) isResourceIdle(resource scalertypes.Resource, resourcesMetricsMap map[string]map[string]int) bool {
+	resourceMetrics, found := resourcesMetricsMap[resource.Name]
+	if !found || len(resource.ScaleResources) == 0 {
+		return false
+	}
+
+	for _, scaleResource := range resource.ScaleResources {
+		value, found := resourceMetrics[scaleResource.GetKubernetesMetricName()]
+		if !found || value > 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,498 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	defaultMaxQueuedBodySize = 10 * 1024 * 1024
+	queuedRequestFileSuffix  = ".request"
+
+	// a failed replay is retried after the interval, doubled on every attempt, before it is left for the next wake-up
+	defaultReplayRetryInterval = time.Second
+	maxReplayAttempts          = 5
+)
+
+// asyncWaker answers the requests to the async routes once they trigger the wake-up of their resources, and replays
+// the queued requests to their targets once the targets are ready
+type asyncWaker struct {
+	routes              []scalertypes.AsyncRoute
+	requestQueue        *requestQueue
+	maxQueuedBodySize   int64
+	replayRetryInterval time.Duration
+
+	// replays of a resource are serialized, so that its queued requests are replayed once and in order
+	replayLocks sync.Map
+}
+
+func newAsyncWaker(options scalertypes.AsyncWakeOptions) (*asyncWaker, error) {
+	aw := &asyncWaker{
+		routes:              options.Routes,
+		maxQueuedBodySize:   options.MaxQueuedBodySize,
+		replayRetryInterval: defaultReplayRetryInterval,
+	}
+	if aw.maxQueuedBodySize == 0 {
+		aw.maxQueuedBodySize = defaultMaxQueuedBodySize
+	}
+
+	for _, route := range options.Routes {
+		if route.QueueRequests && options.QueueDir == "" {
+			return nil, errors.Errorf("Async route %s%s queues requests, but no queue directory is set",
+				route.Host,
+				route.PathPrefix)
+		}
+	}
+
+	if options.QueueDir != "" {
+		requestQueue, err := newRequestQueue(options.QueueDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create request queue")
+		}
+		aw.requestQueue = requestQueue
+	}
+
+	return aw, nil
+}
+
+// getRoute returns the async route of the request, or nil if the request should be served synchronously. the longest
+// matching path prefix wins
+func (aw *asyncWaker) getRoute(req *http.Request) *scalertypes.AsyncRoute {
+	host := req.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	var matchingRoute *scalertypes.AsyncRoute
+	for routeIndex, route := range aw.routes {
+		if route.Host != "" && !strings.EqualFold(route.Host, host) {
+			continue
+		}
+		if !strings.HasPrefix(req.URL.Path, route.PathPrefix) {
+			continue
+		}
+		if matchingRoute == nil || len(route.PathPrefix) > len(matchingRoute.PathPrefix) {
+			matchingRoute = &aw.routes[routeIndex]
+		}
+	}
+
+	return matchingRoute
+}
+
+func (aw *asyncWaker) getReplayLock(resourceName string) *sync.Mutex {
+	replayLock, _ := aw.replayLocks.LoadOrStore(resourceName, &sync.Mutex{})
+	return replayLock.(*sync.Mutex)
+}
+
+// handleAsyncRequest answers the request with the status of its route once it triggered the wake-up of its resources,
+// and if the route queues requests, once the request was stored as well
+func (h *Handler) handleAsyncRequest(res http.ResponseWriter,
+	req *http.Request,
+	route *scalertypes.AsyncRoute,
+	resourceNames []string,
+	resourceWeights []int,
+	resourceTargetURLMap map[string]*url.URL) {
+	targetURL, err := h.selectTargetURL(req, resourceNames, resourceWeights, resourceTargetURLMap)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	targetResourceName := h.getTargetResourceName(targetURL, resourceTargetURLMap)
+
+	if route.QueueRequests {
+		if err := validateQueuedResourceName(targetResourceName); err != nil {
+			h.logger.WarnWith("Refusing to queue request",
+				"resourceName", targetResourceName,
+				"err", err.Error())
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, h.asyncWaker.maxQueuedBodySize+1))
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > h.asyncWaker.maxQueuedBodySize {
+			res.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// the request is acknowledged only once it is stored, so that it is not lost if the DLX restarts
+		if err := h.asyncWaker.requestQueue.push(targetResourceName, targetURL, req, body); err != nil {
+			h.logger.WarnWith("Failed to queue request",
+				"resourceName", targetResourceName,
+				"err", errors.GetErrorStackString(err, 10))
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resourceNamesToStart := resourceNames
+	if h.wakeSelectedTargetOnly {
+		resourceNamesToStart = []string{targetResourceName}
+	}
+	go h.wakeAndReplay(resourceNamesToStart, targetResourceName)
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusAccepted
+	}
+	res.WriteHeader(status)
+}
+
+// replayPendingRequests replays the requests left queued by a previous run, waking their resources first
+func (h *Handler) replayPendingRequests() error {
+	if h.asyncWaker.requestQueue == nil {
+		return nil
+	}
+
+	resourceNames, err := h.asyncWaker.requestQueue.getResourceNames()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get the resources of the queued requests")
+	}
+	for _, resourceName := range resourceNames {
+		go h.wakeAndReplay([]string{resourceName}, resourceName)
+	}
+
+	return nil
+}
+
+// wakeAndReplay wakes the resources, and then replays the requests queued to the target resource
+func (h *Handler) wakeAndReplay(resourceNames []string, targetResourceName string) {
+	if statusResult := h.startResources(resourceNames); statusResult != nil && statusResult.Error != nil {
+		return
+	}
+
+	if h.asyncWaker.requestQueue != nil {
+		h.replayQueuedRequests(targetResourceName)
+	}
+}
+
+// replayQueuedRequests replays the requests queued to the resource in order, until none is left. a request that
+// fails is kept, along with the ones after it, and retried with a backoff. once the attempts run out, the requests are
+// replayed on the next wake-up of the resource
+func (h *Handler) replayQueuedRequests(resourceName string) {
+	replayLock := h.asyncWaker.getReplayLock(resourceName)
+	replayLock.Lock()
+	defer replayLock.Unlock()
+
+	failedReplays := 0
+	for {
+		requestPaths, err := h.asyncWaker.requestQueue.list(resourceName)
+		if err != nil {
+			h.logger.WarnWith("Failed to list queued requests",
+				"resourceName", resourceName,
+				"err", errors.GetErrorStackString(err, 10))
+			return
+		}
+		if len(requestPaths) == 0 {
+			return
+		}
+
+		replayed := true
+		for _, requestPath := range requestPaths {
+			if replayed = h.replayQueuedRequest(resourceName, requestPath); !replayed {
+				break
+			}
+		}
+		if replayed {
+			failedReplays = 0
+			continue
+		}
+
+		failedReplays++
+		if failedReplays >= maxReplayAttempts {
+			h.logger.WarnWith("Failed to replay queued requests, will retry on the next wake-up",
+				"resourceName", resourceName,
+				"attempts", failedReplays)
+			return
+		}
+
+		retryTimer := time.NewTimer(h.asyncWaker.replayRetryInterval << (failedReplays - 1))
+		select {
+		case <-retryTimer.C:
+		case <-h.abortCtx.Done():
+			retryTimer.Stop()
+			return
+		}
+	}
+}
+
+// replayQueuedRequest replays the queued request and removes it from the queue, returning whether it was replayed. a
+// request that can't be read is dropped
+func (h *Handler) replayQueuedRequest(resourceName string, requestPath string) bool {
+	targetURL, queuedRequest, err := h.asyncWaker.requestQueue.read(requestPath)
+	if err != nil {
+
+		// a request that can't be read will not be readable on the next attempt either
+		h.logger.WarnWith("Failed to read queued request, dropping it",
+			"requestPath", requestPath,
+			"err", errors.GetErrorStackString(err, 10))
+		h.asyncWaker.requestQueue.remove(requestPath) // nolint: errcheck
+		return true
+	}
+
+	replayWriter := &replayResponseWriter{header: http.Header{}}
+	h.getOrCreateProxy(targetURL, resourceName).ServeHTTP(replayWriter,
+		h.withWarmUpRetryDeadline(withProxyTargetURL(queuedRequest.WithContext(h.abortCtx), targetURL), resourceName))
+	if replayWriter.getStatus() >= http.StatusInternalServerError {
+		h.logger.WarnWith("Failed to replay queued request",
+			"resourceName", resourceName,
+			"requestPath", requestPath,
+			"status", replayWriter.getStatus())
+		return false
+	}
+
+	h.logger.DebugWith("Replayed queued request",
+		"resourceName", resourceName,
+		"requestPath", requestPath,
+		"status", replayWriter.getStatus())
+	if err := h.asyncWaker.requestQueue.remove(requestPath); err != nil {
+		h.logger.WarnWith("Failed to remove replayed request",
+			"requestPath", requestPath,
+			"err", errors.GetErrorStackString(err, 10))
+		return false
+	}
+
+	return true
+}
+
+// replayResponseWriter records the status of a replayed request, and discards its response
+type replayResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (rrw *replayResponseWriter) Header() http.Header {
+	return rrw.header
+}
+
+func (rrw *replayResponseWriter) Write(data []byte) (int, error) {
+	if rrw.status == 0 {
+		rrw.status = http.StatusOK
+	}
+	return len(data), nil
+}
+
+func (rrw *replayResponseWriter) WriteHeader(status int) {
+	if rrw.status == 0 {
+		rrw.status = status
+	}
+}
+
+func (rrw *replayResponseWriter) getStatus() int {
+	if rrw.status == 0 {
+		return http.StatusOK
+	}
+	return rrw.status
+}
+
+// requestQueue stores requests on disk, a directory per resource and a file per request. the files are named by the
+// time they were queued at, so that listing them returns them in order, and each starts with the target URL of the
+// request followed by the request in its wire format
+type requestQueue struct {
+	dir      string
+	sequence atomic.Uint64
+}
+
+func newRequestQueue(dir string) (*requestQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "Failed to create queue directory %s", dir)
+	}
+
+	return &requestQueue{
+		dir: dir,
+	}, nil
+}
+
+// push stores the request durably - it is written to a temporary file that is synced and only then renamed
+func (rq *requestQueue) push(resourceName string, targetURL *url.URL, req *http.Request, body []byte) error {
+	resourceDir, err := rq.getResourceDir(resourceName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get queue directory")
+	}
+	if err := os.MkdirAll(resourceDir, 0700); err != nil {
+		return errors.Wrapf(err, "Failed to create queue directory of resource %s", resourceName)
+	}
+
+	queuedRequest := req.Clone(req.Context())
+	queuedRequest.Body = io.NopCloser(bytes.NewReader(body))
+	queuedRequest.ContentLength = int64(len(body))
+	queuedRequest.TransferEncoding = nil
+
+	// the dump writes the headers as is, and the length of the body is read back from them
+	queuedRequest.Header.Del("Transfer-Encoding")
+	queuedRequest.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	requestDump, err := httputil.DumpRequest(queuedRequest, true)
+	if err != nil {
+		return errors.Wrap(err, "Failed to dump request")
+	}
+
+	fileName := fmt.Sprintf("%020d-%010d%s", time.Now().UnixNano(), rq.sequence.Add(1), queuedRequestFileSuffix)
+	temporaryPath := filepath.Join(resourceDir, "."+fileName+".tmp")
+	file, err := os.OpenFile(temporaryPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create request file")
+	}
+
+	_, err = file.WriteString(targetURL.String() + "\n")
+	if err == nil {
+		_, err = file.Write(requestDump)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporaryPath) // nolint: errcheck
+		return errors.Wrap(err, "Failed to write request file")
+	}
+
+	if err := os.Rename(temporaryPath, filepath.Join(resourceDir, fileName)); err != nil {
+		os.Remove(temporaryPath) // nolint: errcheck
+		return errors.Wrap(err, "Failed to rename request file")
+	}
+
+	return nil
+}
+
+// list returns the paths of the requests queued to the resource, in the order they were queued
+func (rq *requestQueue) list(resourceName string) ([]string, error) {
+	resourceDir, err := rq.getResourceDir(resourceName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get queue directory")
+	}
+
+	dirEntries, err := os.ReadDir(resourceDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "Failed to read queue directory")
+	}
+
+	var requestPaths []string
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() &&
+			strings.HasSuffix(dirEntry.Name(), queuedRequestFileSuffix) &&
+			!strings.HasPrefix(dirEntry.Name(), ".") {
+			requestPaths = append(requestPaths, filepath.Join(resourceDir, dirEntry.Name()))
+		}
+	}
+	sort.Strings(requestPaths)
+
+	return requestPaths, nil
+}
+
+func (rq *requestQueue) read(requestPath string) (*url.URL, *http.Request, error) {
+	requestFile, err := os.ReadFile(requestPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to read request file")
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(requestFile))
+	targetURLLine, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to read target URL")
+	}
+	targetURL, err := url.Parse(strings.TrimSuffix(targetURLLine, "\n"))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse target URL")
+	}
+
+	queuedRequest, err := http.ReadRequest(reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse request")
+	}
+
+	return targetURL, queuedRequest, nil
+}
+
+func (rq *requestQueue) remove(requestPath string) error {
+	if err := os.Remove(requestPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Failed to remove request file")
+	}
+	return nil
+}
+
+// getResourceNames returns the names of the resources that have queued requests
+func (rq *requestQueue) getResourceNames() ([]string, error) {
+	dirEntries, err := os.ReadDir(rq.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read queue directory")
+	}
+
+	var resourceNames []string
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		resourceName := dirEntry.Name()
+		if validateQueuedResourceName(resourceName) != nil {
+			continue
+		}
+		if requestPaths, err := rq.list(resourceName); err == nil && len(requestPaths) > 0 {
+			resourceNames = append(resourceNames, resourceName)
+		}
+	}
+
+	return resourceNames, nil
+}
+
+func (rq *requestQueue) getResourceDir(resourceName string) (string, error) {
+	if err := validateQueuedResourceName(resourceName); err != nil {
+		return "", err
+	}
+	return filepath.Join(rq.dir, resourceName), nil
+}
+
+// validateQueuedResourceName makes sure the resource name can be used as is as the name of its queue directory - a
+// DNS-1123 label can't be "." or "..", nor contain a path separator, so it can't escape the queue directory
+func validateQueuedResourceName(resourceName string) error {
+	if errs := validation.IsDNS1123Label(resourceName); len(errs) > 0 {
+		return errors.Errorf("Invalid resource name %s: %s", resourceName, strings.Join(errs, ", "))
+	}
+	return nil
+}
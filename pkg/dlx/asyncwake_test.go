@@ -0,0 +1,383 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	resourcescalerMock "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type replayedRequest struct {
+	path string
+	body string
+}
+
+type AsyncWakeTestSuite struct {
+	suite.Suite
+	logger           logger.Logger
+	scaler           *resourcescalerMock.ResourceScaler
+	queueDir         string
+	targetServer     *httptest.Server
+	targetPort       int
+	targetStatus     atomic.Int32
+	replayedRequests chan replayedRequest
+}
+
+func (suite *AsyncWakeTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *AsyncWakeTestSuite) SetupTest() {
+	suite.scaler = &resourcescalerMock.ResourceScaler{}
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("127.0.0.1", nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+	suite.queueDir = suite.T().TempDir()
+
+	replayedRequests := make(chan replayedRequest, 10)
+	suite.replayedRequests = replayedRequests
+	suite.targetStatus.Store(http.StatusOK)
+	suite.targetServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		status := int(suite.targetStatus.Load())
+		if status == http.StatusOK {
+			replayedRequests <- replayedRequest{path: r.URL.Path, body: string(body)}
+		}
+		w.WriteHeader(status)
+	}))
+	targetServerURL, err := url.Parse(suite.targetServer.URL)
+	suite.Require().NoError(err)
+	suite.targetPort, err = strconv.Atoi(targetServerURL.Port())
+	suite.Require().NoError(err)
+}
+
+func (suite *AsyncWakeTestSuite) TearDownTest() {
+	suite.targetServer.Close()
+}
+
+func (suite *AsyncWakeTestSuite) TestQueueAndReplay() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		After(300 * time.Millisecond).
+		Return(nil)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true},
+	})
+
+	// the request is answered before the resource is ready, and replayed to it once it is
+	startTime := time.Now()
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/hooks/github", "event-1"))
+	suite.Require().Equal(http.StatusAccepted, testResponse.Code)
+	suite.Require().Less(time.Since(startTime), 300*time.Millisecond)
+
+	select {
+	case replayed := <-suite.replayedRequests:
+		suite.Require().Equal("/hooks/github", replayed.path)
+		suite.Require().Equal("event-1", replayed.body)
+	case <-time.After(3 * time.Second):
+		suite.Fail("Queued request was not replayed")
+	}
+	suite.Require().Eventually(func() bool {
+		requestPaths, err := testHandler.asyncWaker.requestQueue.list("test-resource")
+		return err == nil && len(requestPaths) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *AsyncWakeTestSuite) TestFailedReplayIsKept() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.targetStatus.Store(http.StatusInternalServerError)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true, Status: http.StatusOK},
+	})
+
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/hooks/github", "event-1"))
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+
+	// the failed request stays queued, and is replayed by the next run
+	time.Sleep(200 * time.Millisecond)
+	testHandler.abortRequests()
+	requestPaths, err := testHandler.asyncWaker.requestQueue.list("test-resource")
+	suite.Require().NoError(err)
+	suite.Require().Len(requestPaths, 1)
+
+	suite.targetStatus.Store(http.StatusOK)
+	nextTestHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true},
+	})
+	suite.Require().NoError(nextTestHandler.replayPendingRequests())
+	select {
+	case replayed := <-suite.replayedRequests:
+		suite.Require().Equal("event-1", replayed.body)
+	case <-time.After(3 * time.Second):
+		suite.Fail("Pending request was not replayed")
+	}
+}
+
+func (suite *AsyncWakeTestSuite) TestFailedReplayIsRetried() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.targetStatus.Store(http.StatusServiceUnavailable)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true},
+	})
+
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/hooks/github", "event-1"))
+	suite.Require().Equal(http.StatusAccepted, testResponse.Code)
+
+	// the target recovers while the replay is retried, without the resource waking up again
+	time.Sleep(100 * time.Millisecond)
+	suite.targetStatus.Store(http.StatusOK)
+	select {
+	case replayed := <-suite.replayedRequests:
+		suite.Require().Equal("event-1", replayed.body)
+	case <-time.After(3 * time.Second):
+		suite.Fail("Failed request was not retried")
+	}
+	suite.scaler.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+}
+
+func (suite *AsyncWakeTestSuite) TestReplayOnWake() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true},
+	})
+	err := testHandler.asyncWaker.requestQueue.push("test-resource",
+		&url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", suite.targetPort)},
+		suite.createTestRequest("/hooks/github", "event-1"),
+		[]byte("event-1"))
+	suite.Require().NoError(err)
+
+	// a request to a sync route wakes the resource, which replays the requests queued to it
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/api", "request"))
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+
+	replayedBodies := []string{}
+	for len(replayedBodies) < 2 {
+		select {
+		case replayed := <-suite.replayedRequests:
+			replayedBodies = append(replayedBodies, replayed.body)
+		case <-time.After(3 * time.Second):
+			suite.FailNow("Queued request was not replayed")
+		}
+	}
+	suite.Require().ElementsMatch([]string{"request", "event-1"}, replayedBodies)
+}
+
+func (suite *AsyncWakeTestSuite) TestRoutesRequireReadinessCache() {
+	_, err := NewMiddleware(suite.logger,
+		WithResourceScaler(suite.scaler),
+		WithDLXOptions(scalertypes.DLXOptions{
+			TargetNameHeader: "X-Target",
+			AsyncWake: scalertypes.AsyncWakeOptions{
+				Routes: []scalertypes.AsyncRoute{{PathPrefix: "/hooks"}},
+			},
+		}))
+	suite.Require().Error(err)
+
+	// without async routes, the readiness cache is optional
+	_, err = NewMiddleware(suite.logger,
+		WithResourceScaler(suite.scaler),
+		WithDLXOptions(scalertypes.DLXOptions{
+			TargetNameHeader: "X-Target",
+		}))
+	suite.Require().NoError(err)
+}
+
+func (suite *AsyncWakeTestSuite) TestSyncRoute() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks"},
+	})
+
+	// requests to other routes are proxied once their resource is ready
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/api", "request"))
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	replayed := <-suite.replayedRequests
+	suite.Require().Equal("/api", replayed.path)
+}
+
+func (suite *AsyncWakeTestSuite) TestReadyResourceIsProxied() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	readinessCache := NewReadinessCache(suite.logger, time.Minute)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks"},
+	}, WithReadinessCache(readinessCache))
+
+	// the request to a ready resource is proxied, rather than answered and dropped
+	readinessCache.SetReady("test-resource", "127.0.0.1")
+	testResponse := httptest.NewRecorder()
+	testHandler.ServeHTTP(testResponse, suite.createTestRequest("/hooks/github", "event-1"))
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	select {
+	case replayed := <-suite.replayedRequests:
+		suite.Require().Equal("event-1", replayed.body)
+	default:
+		suite.Fail("Request was not proxied")
+	}
+	suite.scaler.AssertNotCalled(suite.T(), "SetScaleCtx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AsyncWakeTestSuite) TestInvalidResourceNameIsNotQueued() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	testHandler := suite.createTestHandler([]scalertypes.AsyncRoute{
+		{PathPrefix: "/hooks", QueueRequests: true},
+	})
+
+	for _, resourceName := range []string{"..", ".", "Test-Resource", "test%2Fresource"} {
+		suite.Run(resourceName, func() {
+			testRequest := suite.createTestRequest("/hooks/github", "event-1")
+			testRequest.Header.Set("X-Target", resourceName)
+			testResponse := httptest.NewRecorder()
+			testHandler.ServeHTTP(testResponse, testRequest)
+			suite.Require().Equal(http.StatusBadRequest, testResponse.Code)
+
+			err := testHandler.asyncWaker.requestQueue.push(resourceName,
+				&url.URL{Scheme: "http", Host: "127.0.0.1"},
+				testRequest,
+				nil)
+			suite.Require().Error(err)
+		})
+	}
+
+	// nothing was written to the queue directory, nor next to it
+	queueDirEntries, err := os.ReadDir(suite.queueDir)
+	suite.Require().NoError(err)
+	suite.Require().Empty(queueDirEntries)
+	parentDirEntries, err := os.ReadDir(filepath.Dir(suite.queueDir))
+	suite.Require().NoError(err)
+	for _, parentDirEntry := range parentDirEntries {
+		suite.Require().False(strings.HasSuffix(parentDirEntry.Name(), queuedRequestFileSuffix))
+	}
+}
+
+func (suite *AsyncWakeTestSuite) TestGetRoute() {
+	testAsyncWaker, err := newAsyncWaker(scalertypes.AsyncWakeOptions{
+		Routes: []scalertypes.AsyncRoute{
+			{PathPrefix: "/hooks"},
+			{PathPrefix: "/hooks/github", Status: http.StatusOK},
+			{Host: "webhooks.example.com", Status: http.StatusNoContent},
+		},
+	})
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		name           string
+		host           string
+		path           string
+		expectedStatus int
+	}{
+		{
+			name:           "Path prefix",
+			host:           "www.example.com",
+			path:           "/hooks/slack",
+			expectedStatus: http.StatusAccepted,
+		}, {
+			name:           "Longest path prefix",
+			host:           "www.example.com",
+			path:           "/hooks/github/push",
+			expectedStatus: http.StatusOK,
+		}, {
+			name:           "Host with port",
+			host:           "webhooks.example.com:8080",
+			path:           "/any",
+			expectedStatus: http.StatusNoContent,
+		}, {
+			name: "No route",
+			host: "www.example.com",
+			path: "/api",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testRequest := httptest.NewRequest("POST", testCase.path, nil)
+			testRequest.Host = testCase.host
+			route := testAsyncWaker.getRoute(testRequest)
+			if testCase.expectedStatus == 0 {
+				suite.Require().Nil(route)
+				return
+			}
+			suite.Require().NotNil(route)
+			status := route.Status
+			if status == 0 {
+				status = http.StatusAccepted
+			}
+			suite.Require().Equal(testCase.expectedStatus, status)
+		})
+	}
+
+	// queueing requires a queue directory
+	_, err = newAsyncWaker(scalertypes.AsyncWakeOptions{
+		Routes: []scalertypes.AsyncRoute{{PathPrefix: "/hooks", QueueRequests: true}},
+	})
+	suite.Require().Error(err)
+}
+
+// --- AsyncWakeTestSuite suite methods ---
+
+func (suite *AsyncWakeTestSuite) createTestHandler(asyncRoutes []scalertypes.AsyncRoute,
+	options ...MiddlewareOption) *Handler {
+	middleware, err := NewMiddleware(suite.logger, append([]MiddlewareOption{
+		WithResourceScaler(suite.scaler),
+		WithReadinessCache(NewReadinessCache(suite.logger, time.Minute)),
+		WithDLXOptions(scalertypes.DLXOptions{
+			TargetNameHeader:         "X-Target",
+			TargetPort:               suite.targetPort,
+			ResourceReadinessTimeout: scalertypes.Duration{Duration: 3 * time.Second},
+			AsyncWake: scalertypes.AsyncWakeOptions{
+				Routes:   asyncRoutes,
+				QueueDir: suite.queueDir,
+			},
+		})}, options...)...)
+	suite.Require().NoError(err)
+	middleware.handler.asyncWaker.replayRetryInterval = 50 * time.Millisecond
+	return middleware.handler
+}
+
+func (suite *AsyncWakeTestSuite) createTestRequest(path string, body string) *http.Request {
+	testRequest := httptest.NewRequest("POST", path, strings.NewReader(body))
+	testRequest.Header.Set("X-Target", "test-resource")
+	return testRequest
+}
+
+func TestAsyncWakeTestSuite(t *testing.T) {
+	suite.Run(t, new(AsyncWakeTestSuite))
+}
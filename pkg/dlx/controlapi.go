@@ -0,0 +1,182 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+const controlAPIPathPrefix = "/_scaler/"
+
+const (
+	wakeStateWaking = "waking"
+	wakeStateReady  = "ready"
+	wakeStateFailed = "failed"
+
+	// the resource was woken up, but is no longer known to be ready - it was scaled back to zero, or its readiness
+	// expired from the readiness cache - so the next request wakes it up again
+	wakeStateIdle = "idle"
+)
+
+// wakeStatusBody reports the current, or last, wake-up of a resource
+type wakeStatusBody struct {
+	Resource  string     `json:"resource"`
+	State     string     `json:"state"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+
+	// how long the wake-up took, or has been taking so far
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	// how long the last successful wake-up took, which the current one is expected to take as well
+	LastWakeDurationSeconds float64 `json:"lastWakeDurationSeconds,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// how many requests are waiting for the resource to start
+	WaitingRequests int64 `json:"waitingRequests"`
+}
+
+// controlAPI wakes resources explicitly, e.g. to pre-warm them before they are needed, and reports their wake-up
+// status. wake-ups go through the resource starter, so they are shared with the wake-ups of proxied requests
+type controlAPI struct {
+	logger  logger.Logger
+	handler *Handler
+	token   string
+}
+
+func newControlAPI(parentLogger logger.Logger, handler *Handler, token string) *controlAPI {
+	return &controlAPI{
+		logger:  parentLogger.GetChild("control-api"),
+		handler: handler,
+		token:   token,
+	}
+}
+
+func (ca *controlAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST "+controlAPIPathPrefix+"wake/{resource}", ca.authenticate(ca.handleWake))
+	mux.HandleFunc("GET "+controlAPIPathPrefix+"status/{resource}", ca.authenticate(ca.handleStatus))
+
+	// the control API paths are never proxied
+	mux.Handle(controlAPIPathPrefix, http.NotFoundHandler())
+}
+
+// authenticate serves only the requests bearing the token
+func (ca *controlAPI) authenticate(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(ca.token)) != 1 {
+			res.Header().Set("WWW-Authenticate", "Bearer")
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handlerFunc(res, req)
+	}
+}
+
+// handleWake wakes the resource, and responds with its status once it is ready. with async=true it responds as soon as
+// the wake-up is triggered, and the status should be polled until the resource is ready
+func (ca *controlAPI) handleWake(res http.ResponseWriter, req *http.Request) {
+	resourceName := req.PathValue("resource")
+	async, _ := strconv.ParseBool(req.URL.Query().Get("async"))
+
+	ca.logger.DebugWith("Waking resource", "resourceName", resourceName, "async", async)
+	if async {
+		ca.handler.resourceStarter.triggerBackgroundResourceStart(resourceName)
+		ca.writeWakeStatus(res, http.StatusAccepted, resourceName)
+		return
+	}
+
+	if statusResult := ca.handler.startResources([]string{resourceName}); statusResult != nil && statusResult.Error != nil {
+		ca.handler.writeResourceStartError(res, req, statusResult)
+		return
+	}
+	ca.writeWakeStatus(res, http.StatusOK, resourceName)
+}
+
+func (ca *controlAPI) handleStatus(res http.ResponseWriter, req *http.Request) {
+	resourceName := req.PathValue("resource")
+	if _, found := ca.handler.resourceStarter.getWakeStatus(resourceName); !found {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+	ca.writeWakeStatus(res, http.StatusOK, resourceName)
+}
+
+func (ca *controlAPI) writeWakeStatus(res http.ResponseWriter, status int, resourceName string) {
+	body, err := json.Marshal(ca.getWakeStatusBody(resourceName))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	res.Write(body) // nolint: errcheck
+}
+
+// getWakeStatusBody reports the wake-up of the resource. with the readiness cache, a woken resource is reported as
+// ready only as long as the cache holds it as ready. without it, the DLX can't tell once the resource is scaled back to
+// zero, so the result of the last wake-up is reported
+func (ca *controlAPI) getWakeStatusBody(resourceName string) wakeStatusBody {
+	resourceStarter := ca.handler.resourceStarter
+	wakeStatus, found := resourceStarter.getWakeStatus(resourceName)
+	if !found {
+		return wakeStatusBody{
+			Resource:        resourceName,
+			State:           wakeStateWaking,
+			WaitingRequests: resourceStarter.getResourceWaitingRequests(resourceName),
+		}
+	}
+
+	body := wakeStatusBody{
+		Resource:                resourceName,
+		StartTime:               &wakeStatus.startTime,
+		LastWakeDurationSeconds: wakeStatus.lastWakeDuration.Seconds(),
+		WaitingRequests:         resourceStarter.getResourceWaitingRequests(resourceName),
+	}
+	switch {
+	case wakeStatus.endTime.IsZero():
+		body.State = wakeStateWaking
+		body.DurationSeconds = time.Since(wakeStatus.startTime).Seconds()
+	case wakeStatus.err != nil:
+		body.State = wakeStateFailed
+		body.EndTime = &wakeStatus.endTime
+		body.DurationSeconds = wakeStatus.endTime.Sub(wakeStatus.startTime).Seconds()
+		body.Error = wakeStatus.err.Error()
+	case resourceStarter.readinessCache != nil && !resourceStarter.isResourceReady(resourceName):
+		body.State = wakeStateIdle
+		body.EndTime = &wakeStatus.endTime
+		body.DurationSeconds = wakeStatus.endTime.Sub(wakeStatus.startTime).Seconds()
+	default:
+		body.State = wakeStateReady
+		body.EndTime = &wakeStatus.endTime
+		body.DurationSeconds = wakeStatus.endTime.Sub(wakeStatus.startTime).Seconds()
+	}
+
+	return body
+}
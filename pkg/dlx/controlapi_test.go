@@ -0,0 +1,241 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	resourcescalerMock "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testControlAPIToken = "test-token"
+
+type ControlAPITestSuite struct {
+	suite.Suite
+	logger logger.Logger
+	scaler *resourcescalerMock.ResourceScaler
+	mux    *http.ServeMux
+}
+
+func (suite *ControlAPITestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *ControlAPITestSuite) SetupTest() {
+	suite.scaler = &resourcescalerMock.ResourceScaler{}
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+	suite.createControlAPI()
+}
+
+func (suite *ControlAPITestSuite) TestAuthentication() {
+	for _, testCase := range []struct {
+		name          string
+		authorization string
+	}{
+		{
+			name: "No token",
+		}, {
+			name:          "Wrong token",
+			authorization: "Bearer wrong-token",
+		}, {
+			name:          "Wrong scheme",
+			authorization: "Basic " + testControlAPIToken,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testRequest := httptest.NewRequest("GET", "/_scaler/status/test-resource", nil)
+			if testCase.authorization != "" {
+				testRequest.Header.Set("Authorization", testCase.authorization)
+			}
+			testResponse := httptest.NewRecorder()
+			suite.mux.ServeHTTP(testResponse, testRequest)
+			suite.Require().Equal(http.StatusUnauthorized, testResponse.Code)
+			suite.Require().Equal("Bearer", testResponse.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func (suite *ControlAPITestSuite) TestSyncWake() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	testResponse := suite.sendRequest("POST", "/_scaler/wake/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	wakeStatus := suite.parseWakeStatus(testResponse)
+	suite.Require().Equal("test-resource", wakeStatus.Resource)
+	suite.Require().Equal(wakeStateReady, wakeStatus.State)
+	suite.Require().NotNil(wakeStatus.StartTime)
+	suite.Require().NotNil(wakeStatus.EndTime)
+
+	// the status of a woken resource is kept
+	testResponse = suite.sendRequest("GET", "/_scaler/status/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	suite.Require().Equal(wakeStateReady, suite.parseWakeStatus(testResponse).State)
+}
+
+func (suite *ControlAPITestSuite) TestAsyncWake() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		After(300 * time.Millisecond).
+		Return(nil)
+
+	startTime := time.Now()
+	testResponse := suite.sendRequest("POST", "/_scaler/wake/test-resource?async=true")
+	suite.Require().Equal(http.StatusAccepted, testResponse.Code)
+	suite.Require().Less(time.Since(startTime), 300*time.Millisecond)
+	suite.Require().Equal(wakeStateWaking, suite.parseWakeStatus(testResponse).State)
+
+	suite.Require().Eventually(func() bool {
+		testResponse := suite.sendRequest("GET", "/_scaler/status/test-resource")
+		return testResponse.Code == http.StatusOK &&
+			suite.parseWakeStatus(testResponse).State == wakeStateReady
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+func (suite *ControlAPITestSuite) TestAsyncWakeAfterPreviousWake() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		After(300 * time.Millisecond).
+		Return(nil)
+	handler := suite.createControlAPI()
+
+	testResponse := suite.sendRequest("POST", "/_scaler/wake/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	suite.Require().Equal(wakeStateReady, suite.parseWakeStatus(testResponse).State)
+
+	// the new wake-up is reported right away, rather than the previous one
+	handler.resourceStarter.deleteResourceSink("test-resource")
+	testResponse = suite.sendRequest("POST", "/_scaler/wake/test-resource?async=true")
+	suite.Require().Equal(http.StatusAccepted, testResponse.Code)
+	wakeStatus := suite.parseWakeStatus(testResponse)
+	suite.Require().Equal(wakeStateWaking, wakeStatus.State)
+	suite.Require().Nil(wakeStatus.EndTime)
+	suite.Require().NotZero(wakeStatus.LastWakeDurationSeconds)
+
+	testResponse = suite.sendRequest("GET", "/_scaler/status/test-resource")
+	suite.Require().Equal(wakeStateWaking, suite.parseWakeStatus(testResponse).State)
+}
+
+func (suite *ControlAPITestSuite) TestReadinessInvalidated() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("test-service", nil)
+	readinessCache := NewReadinessCache(suite.logger, time.Minute)
+	suite.createControlAPI(WithReadinessCache(readinessCache))
+
+	testResponse := suite.sendRequest("POST", "/_scaler/wake/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	suite.Require().Equal(wakeStateReady, suite.parseWakeStatus(testResponse).State)
+
+	// once the resource is scaled back to zero, it is no longer reported as ready
+	readinessCache.InvalidateService("test-service")
+	testResponse = suite.sendRequest("GET", "/_scaler/status/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	wakeStatus := suite.parseWakeStatus(testResponse)
+	suite.Require().Equal(wakeStateIdle, wakeStatus.State)
+	suite.Require().NotNil(wakeStatus.EndTime)
+}
+
+func (suite *ControlAPITestSuite) TestFailedWake() {
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("Scale failed"))
+
+	testResponse := suite.sendRequest("POST", "/_scaler/wake/test-resource")
+	suite.Require().GreaterOrEqual(testResponse.Code, http.StatusInternalServerError)
+
+	testResponse = suite.sendRequest("GET", "/_scaler/status/test-resource")
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+	wakeStatus := suite.parseWakeStatus(testResponse)
+	suite.Require().Equal(wakeStateFailed, wakeStatus.State)
+	suite.Require().NotEmpty(wakeStatus.Error)
+}
+
+func (suite *ControlAPITestSuite) TestUnknownPaths() {
+	for _, testCase := range []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{
+			name:           "Resource never woken",
+			method:         "GET",
+			path:           "/_scaler/status/test-resource",
+			expectedStatus: http.StatusNotFound,
+		}, {
+			name:           "Unknown control path",
+			method:         "GET",
+			path:           "/_scaler/unknown",
+			expectedStatus: http.StatusNotFound,
+		}, {
+			name:           "Wrong method",
+			method:         "GET",
+			path:           "/_scaler/wake/test-resource",
+			expectedStatus: http.StatusNotFound,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testResponse := suite.sendRequest(testCase.method, testCase.path)
+			suite.Require().Equal(testCase.expectedStatus, testResponse.Code)
+		})
+	}
+}
+
+// --- ControlAPITestSuite suite methods ---
+
+func (suite *ControlAPITestSuite) createControlAPI(options ...MiddlewareOption) *Handler {
+	middleware, err := NewMiddleware(suite.logger, append([]MiddlewareOption{
+		WithResourceScaler(suite.scaler),
+		WithTargetNameHeader("X-Target"),
+		WithResourceReadinessTimeout(3 * time.Second)}, options...)...)
+	suite.Require().NoError(err)
+
+	suite.mux = http.NewServeMux()
+	newControlAPI(suite.logger, middleware.handler, testControlAPIToken).register(suite.mux)
+	return middleware.handler
+}
+
+func (suite *ControlAPITestSuite) sendRequest(method string, path string) *httptest.ResponseRecorder {
+	testRequest := httptest.NewRequest(method, path, nil)
+	testRequest.Header.Set("Authorization", "Bearer "+testControlAPIToken)
+	testResponse := httptest.NewRecorder()
+	suite.mux.ServeHTTP(testResponse, testRequest)
+	return testResponse
+}
+
+func (suite *ControlAPITestSuite) parseWakeStatus(testResponse *httptest.ResponseRecorder) wakeStatusBody {
+	var wakeStatus wakeStatusBody
+	suite.Require().NoError(json.Unmarshal(testResponse.Body.Bytes(), &wakeStatus))
+	return wakeStatus
+}
+
+func TestControlAPITestSuite(t *testing.T) {
+	suite.Run(t, new(ControlAPITestSuite))
+}
@@ -22,20 +22,37 @@ package dlx
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/v3io/scaler/pkg/kube"
 	"github.com/v3io/scaler/pkg/scalertypes"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// how long aborted requests are given to be answered, once the drain deadline of a shutdown passed
+const abortedRequestsGracePeriod = 5 * time.Second
+
+// how often the requests waiting for resources to start are logged, while there are any
+const waitingRequestsLogInterval = 30 * time.Second
+
 type DLX struct {
-	logger  logger.Logger
-	handler Handler
-	server  *http.Server
-	watcher *kube.IngressWatcher
+	logger               logger.Logger
+	middleware           *Middleware
+	handler              *Handler
+	server               *http.Server
+	watcher              *kube.IngressWatcher
+	endpointSliceWatcher *kube.EndpointSliceWatcher
+	tcpProxy             *TCPProxy
+	certificateStore     *certificateStore
+	stopChan             chan struct{}
+	stopOnce             sync.Once
 }
 
 func NewDLX(parentLogger logger.Logger,
@@ -44,12 +61,25 @@ func NewDLX(parentLogger logger.Logger,
 	childLogger := parentLogger.GetChild("dlx")
 	childLogger.InfoWith("Creating DLX",
 		"options", options)
-	resourceStarter, err := NewResourceStarter(childLogger,
-		resourceScaler,
-		options.Namespace,
-		options.ResourceReadinessTimeout.Duration)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create function starter")
+
+	var readinessCache *ReadinessCache
+	var endpointSliceWatcher *kube.EndpointSliceWatcher
+	if options.ReadinessCacheTTL.Duration > 0 {
+		readinessCache = NewReadinessCache(childLogger, options.ReadinessCacheTTL.Duration)
+
+		// a resource scaled to zero loses its service endpoints, which invalidates its readiness
+		var err error
+		endpointSliceWatcher, err = kube.NewEndpointSliceWatcher(
+			context.Background(),
+			childLogger,
+			options.KubeClientSet,
+			readinessCache.InvalidateService,
+			options.ResyncInterval,
+			options.Namespace,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create endpoint slice watcher")
+		}
 	}
 
 	watcher, err := kube.NewIngressWatcher(
@@ -57,6 +87,7 @@ func NewDLX(parentLogger logger.Logger,
 		childLogger,
 		options.KubeClientSet,
 		options.ResolveTargetsFromIngressCallback,
+		options.ResolveTargetWeightsFromIngressCallback,
 		options.ResyncInterval,
 		options.Namespace,
 		options.LabelSelector,
@@ -65,43 +96,230 @@ func NewDLX(parentLogger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create ingress watcher")
 	}
 
-	handler, err := NewHandler(childLogger,
-		resourceStarter,
-		resourceScaler,
-		options.TargetNameHeader,
-		options.TargetPathHeader,
-		options.TargetPort,
-		options.MultiTargetStrategy,
-		watcher.GetIngressHostCacheReader())
+	// the DLX serves the middleware, resolving the resources of requests by the watched ingresses
+	middleware, err := NewMiddleware(childLogger,
+		WithDLXOptions(options),
+		WithResourceScaler(resourceScaler),
+		withIngressCache(watcher.GetIngressHostCacheReader()),
+		WithReadinessCache(readinessCache))
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create handler")
+		return nil, errors.Wrap(err, "Failed to create middleware")
+	}
+
+	var tcpProxy *TCPProxy
+	if len(options.TCPProxyListeners) > 0 {
+		if tcpProxy, err = NewTCPProxy(childLogger,
+			middleware.resourceStarter,
+			resourceScaler,
+			options.TCPProxyListeners,
+			options.TCPProxyDialTimeout.Duration); err != nil {
+			return nil, errors.Wrap(err, "Failed to create TCP proxy")
+		}
+	}
+
+	// each DLX serves its own mux, so that several may run in one process
+	mux := http.NewServeMux()
+	server := &http.Server{
+		Addr:              options.ListenAddress,
+		Handler:           mux,
+		ReadTimeout:       options.Server.ReadTimeout.Duration,
+		ReadHeaderTimeout: options.Server.ReadHeaderTimeout.Duration,
+		WriteTimeout:      options.Server.WriteTimeout.Duration,
+		IdleTimeout:       options.Server.IdleTimeout.Duration,
+		MaxHeaderBytes:    options.Server.MaxHeaderBytes,
+	}
+	if options.Server.DisableKeepAlives {
+		server.SetKeepAlivesEnabled(false)
+	}
+
+	var certificates *certificateStore
+	if options.TLS.Enabled() {
+		var tlsSecretWatcher *kube.TLSSecretWatcher
+		if options.TLS.IngressSecrets {
+			if tlsSecretWatcher, err = kube.NewTLSSecretWatcher(
+				context.Background(),
+				childLogger,
+				options.KubeClientSet,
+				options.ResyncInterval,
+				options.Namespace,
+				options.LabelSelector,
+			); err != nil {
+				return nil, errors.Wrap(err, "Failed to create TLS secret watcher")
+			}
+		}
+
+		if certificates, err = newCertificateStore(childLogger, options.TLS, tlsSecretWatcher); err != nil {
+			return nil, errors.Wrap(err, "Failed to create certificate store")
+		}
+
+		// must be set before configuring HTTP/2, which adds its protocol to the TLS configuration
+		server.TLSConfig = &tls.Config{
+			GetCertificate: certificates.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}
+	}
+
+	if options.EnableHTTP2 {
+		http2Server := &http2.Server{}
+		if err := http2.ConfigureServer(server, http2Server); err != nil {
+			return nil, errors.Wrap(err, "Failed to configure HTTP/2 server")
+		}
+
+		// upgrades cleartext HTTP/2 connections, and passes all the others through to the mux. the upgraded connections
+		// are hijacked, so they are tracked like the other upgraded connections
+		server.Handler = newH2CTrackingHandler(h2c.NewHandler(mux, http2Server), middleware.handler.upgradeTracker)
+	}
+
+	newDLX := &DLX{
+		logger:               childLogger,
+		middleware:           middleware,
+		handler:              middleware.handler,
+		server:               server,
+		watcher:              watcher,
+		endpointSliceWatcher: endpointSliceWatcher,
+		tcpProxy:             tcpProxy,
+		certificateStore:     certificates,
+		stopChan:             make(chan struct{}),
+	}
+	mux.Handle("/", middleware)
+	if options.ControlAPI.Token != "" {
+		newControlAPI(childLogger, middleware.handler, options.ControlAPI.Token).register(mux)
 	}
 
-	return &DLX{
-		logger:  childLogger,
-		handler: handler,
-		server: &http.Server{
-			Addr: options.ListenAddress,
-		},
-		watcher: watcher,
-	}, nil
+	return newDLX, nil
+}
+
+// Handler returns the handler served by the DLX, for serving it by another server
+func (d *DLX) Handler() http.Handler {
+	return d.server.Handler
 }
 
 func (d *DLX) Start() error {
 	d.logger.DebugWith("Starting", "server", d.server.Addr)
-	http.HandleFunc("/", d.handler.HandleFunc)
 
 	// Start the ingress watcher synchronously to ensure cache is fully synced before DLX begins handling traffic
 	if err := d.watcher.Start(); err != nil {
 		return errors.Wrap(err, "Failed to start ingress watcher")
 	}
 
+	if d.endpointSliceWatcher != nil {
+		if err := d.endpointSliceWatcher.Start(); err != nil {
+			return errors.Wrap(err, "Failed to start endpoint slice watcher")
+		}
+	}
+
+	// the requests queued by async routes before a restart are replayed once their resources wake up
+	if err := d.middleware.Start(); err != nil {
+		return errors.Wrap(err, "Failed to start middleware")
+	}
+
+	if d.tcpProxy != nil {
+		if err := d.tcpProxy.Start(); err != nil {
+			return errors.Wrap(err, "Failed to start TCP proxy")
+		}
+	}
+
+	go d.logWaitingRequests()
+
+	if d.certificateStore != nil {
+		if err := d.certificateStore.start(); err != nil {
+			return errors.Wrap(err, "Failed to start certificate store")
+		}
+
+		// the certificates are selected by the TLS configuration, rather than loaded from files by the server
+		go d.server.ListenAndServeTLS("", "") // nolint: errcheck
+		return nil
+	}
+
 	go d.server.ListenAndServe() // nolint: errcheck
 	return nil
 }
 
-func (d *DLX) Stop(context context.Context) error {
-	d.logger.DebugWith("Stopping", "server", d.server.Addr)
+// Stop stops accepting new connections, and lets the in-flight requests finish until the context is done - including
+// the requests waiting for their resources to wake up. the requests still in flight by then are aborted with a 503,
+// which is expected on shutdown and is only logged. stopping again does nothing
+func (d *DLX) Stop(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		d.stop(ctx)
+	})
+	return nil
+}
+
+func (d *DLX) stop(ctx context.Context) {
+	d.logger.InfoWith("Stopping", "server", d.server.Addr)
+
+	// the TCP proxy connections are drained along with the requests, under the same deadline
+	tcpProxyErrChan := make(chan error, 1)
+	go func() {
+		if d.tcpProxy == nil {
+			tcpProxyErrChan <- nil
+			return
+		}
+		tcpProxyErrChan <- d.tcpProxy.Stop(ctx)
+	}()
+
+	close(d.stopChan)
+	drainErr := d.server.Shutdown(ctx)
+	if drainErr == nil {
+
+		// the server does not track hijacked connections, so wait for the upgraded connections to be closed separately
+		drainErr = d.handler.upgradeTracker.wait(ctx)
+	}
+	if drainErr != nil {
+		d.logger.WarnWith("Requests were not drained in time, aborting them",
+			"err", drainErr.Error())
+		d.abortRequests()
+	}
+	tcpProxyErr := <-tcpProxyErrChan
+
 	d.watcher.Stop()
-	return d.server.Shutdown(context)
+	if d.endpointSliceWatcher != nil {
+		d.endpointSliceWatcher.Stop()
+	}
+	if d.certificateStore != nil {
+		d.certificateStore.stop()
+	}
+	d.middleware.Stop()
+
+	if tcpProxyErr != nil {
+		d.logger.WarnWith("TCP proxy connections were not drained in time, closed them",
+			"err", tcpProxyErr.Error())
+	}
+
+	d.logger.Info("Stopped")
+}
+
+// logWaitingRequests periodically logs the requests waiting for resources to start, which would otherwise be observable
+// only once they are rejected for exceeding the waiting requests limits
+func (d *DLX) logWaitingRequests() {
+	ticker := time.NewTicker(waitingRequestsLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopChan:
+			return
+		case <-ticker.C:
+			totalWaitingRequests, waitingRequestsPerResource := d.handler.resourceStarter.GetWaitingRequests()
+			if totalWaitingRequests == 0 {
+				continue
+			}
+			d.logger.InfoWith("Requests are waiting for resources to start",
+				"totalWaitingRequests", totalWaitingRequests,
+				"waitingRequestsPerResource", waitingRequestsPerResource)
+		}
+	}
+}
+
+// abortRequests aborts the requests still in flight, and gives them a grace period to be answered before their
+// connections are closed
+func (d *DLX) abortRequests() {
+	d.handler.abortRequests()
+
+	graceCtx, cancel := context.WithTimeout(context.Background(), abortedRequestsGracePeriod)
+	defer cancel()
+	if err := d.server.Shutdown(graceCtx); err != nil {
+		d.server.Close() // nolint: errcheck
+	}
+	d.handler.upgradeTracker.wait(graceCtx) // nolint: errcheck
 }
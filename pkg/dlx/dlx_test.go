@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	resourcescalerMock "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type DLXTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+}
+
+func (suite *DLXTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *DLXTestSuite) TestServerOptions() {
+	testDLX := suite.createTestDLX(scalertypes.ServerOptions{
+		ReadTimeout:       scalertypes.Duration{Duration: time.Second},
+		ReadHeaderTimeout: scalertypes.Duration{Duration: 2 * time.Second},
+		WriteTimeout:      scalertypes.Duration{Duration: 3 * time.Second},
+		IdleTimeout:       scalertypes.Duration{Duration: 4 * time.Second},
+		MaxHeaderBytes:    4096,
+	})
+
+	suite.Require().Equal(time.Second, testDLX.server.ReadTimeout)
+	suite.Require().Equal(2*time.Second, testDLX.server.ReadHeaderTimeout)
+	suite.Require().Equal(3*time.Second, testDLX.server.WriteTimeout)
+	suite.Require().Equal(4*time.Second, testDLX.server.IdleTimeout)
+	suite.Require().Equal(4096, testDLX.server.MaxHeaderBytes)
+}
+
+func (suite *DLXTestSuite) TestDedicatedHandler() {
+	firstDLX := suite.createTestDLX(scalertypes.ServerOptions{})
+	secondDLX := suite.createTestDLX(scalertypes.ServerOptions{})
+
+	// each DLX serves its own handler, and nothing is registered on the default mux
+	for _, testDLX := range []*DLX{firstDLX, secondDLX} {
+		testResponse := httptest.NewRecorder()
+		testDLX.Handler().ServeHTTP(testResponse, httptest.NewRequest("GET", "http://unknown.example.com/", nil))
+		suite.Require().Equal(http.StatusBadRequest, testResponse.Code)
+	}
+	_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest("GET", "/", nil))
+	suite.Require().Empty(pattern)
+}
+
+func (suite *DLXTestSuite) TestStopTwice() {
+	testDLX := suite.createTestDLX(scalertypes.ServerOptions{})
+	suite.Require().NoError(testDLX.Stop(context.Background()))
+	suite.Require().NoError(testDLX.Stop(context.Background()))
+}
+
+func (suite *DLXTestSuite) TestH2CConnectionsAreTracked() {
+	testDLX := suite.createTestDLX(scalertypes.ServerOptions{}, func(options *scalertypes.DLXOptions) {
+		options.EnableHTTP2 = true
+	})
+	testServer := httptest.NewServer(testDLX.Handler())
+	defer testServer.Close()
+
+	// cleartext HTTP/2 with prior knowledge
+	h2cTransport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	testResponse, err := (&http.Client{Transport: h2cTransport}).Get(testServer.URL + "/")
+	suite.Require().NoError(err)
+	testResponse.Body.Close() // nolint: errcheck
+	suite.Require().Equal(2, testResponse.ProtoMajor)
+
+	// the hijacked connection is drained along with the other upgraded connections
+	suite.Require().Equal(1, testDLX.handler.upgradeTracker.count())
+	h2cTransport.CloseIdleConnections()
+	suite.Require().Eventually(func() bool {
+		return testDLX.handler.upgradeTracker.count() == 0
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+// --- DLXTestSuite suite methods ---
+
+func (suite *DLXTestSuite) createTestDLX(serverOptions scalertypes.ServerOptions,
+	optionsModifiers ...func(*scalertypes.DLXOptions)) *DLX {
+	options := scalertypes.DLXOptions{
+		Namespace:                "default",
+		TargetNameHeader:         "X-Nuclio-Target",
+		TargetPort:               8080,
+		ListenAddress:            ":0",
+		ResourceReadinessTimeout: scalertypes.Duration{Duration: time.Second},
+		KubeClientSet:            fake.NewSimpleClientset(),
+		Server:                   serverOptions,
+	}
+	for _, optionsModifier := range optionsModifiers {
+		optionsModifier(&options)
+	}
+
+	testDLX, err := NewDLX(suite.logger, &resourcescalerMock.ResourceScaler{}, options)
+	suite.Require().NoError(err)
+	return testDLX
+}
+
+func TestDLXTestSuite(t *testing.T) {
+	suite.Run(t, new(DLXTestSuite))
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+)
+
+const defaultErrorPageRefreshInterval = 5 * time.Second
+
+var defaultErrorPageTemplate = template.Must(template.New("error-page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+{{- if .RetryAfter}}
+<meta http-equiv="refresh" content="{{.RetryAfter}}">
+{{- end}}
+<title>{{if .Waking}}Waking up{{else}}Failed to wake up{{end}} {{.Resource}}</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 20vh">
+<h1>{{if .Waking}}{{.Resource}} is waking up{{else}}{{.Resource}} could not be woken up{{end}}</h1>
+<p>{{.Reason}}</p>
+{{- if .RetryAfter}}
+<p>Retrying in {{.RetryAfter}} seconds&hellip;</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// errorPageData is passed to the error page templates
+type errorPageData struct {
+	Resource string
+	Reason   string
+
+	// true while the resource is still waking up, as opposed to having failed to wake up
+	Waking bool
+
+	// seconds until the page is refreshed, or 0 if it is not refreshed
+	RetryAfter int
+}
+
+// resourceErrorBody is the body of the error responses to API clients
+type resourceErrorBody struct {
+	Resource   string `json:"resource"`
+	Reason     string `json:"reason"`
+	RetryAfter int    `json:"retryAfter,omitempty"`
+}
+
+// errorPageWriter writes the bodies of the responses to requests whose resources failed to start - an auto refreshing
+// page to browsers, and a JSON body to API clients
+type errorPageWriter struct {
+	refreshInterval time.Duration
+	defaultTemplate *template.Template
+	hostTemplates   map[string]*template.Template
+}
+
+func newErrorPageWriter(options scalertypes.ErrorPageOptions) (*errorPageWriter, error) {
+	epw := &errorPageWriter{
+		refreshInterval: options.RefreshInterval.Duration,
+		defaultTemplate: defaultErrorPageTemplate,
+		hostTemplates:   map[string]*template.Template{},
+	}
+	if epw.refreshInterval == 0 {
+		epw.refreshInterval = defaultErrorPageRefreshInterval
+	}
+
+	if options.TemplateFile != "" {
+		defaultTemplate, err := template.ParseFiles(options.TemplateFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse error page template")
+		}
+		epw.defaultTemplate = defaultTemplate
+	}
+
+	for host, templateFile := range options.HostTemplateFiles {
+		hostTemplate, err := template.ParseFiles(templateFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse error page template of host %s", host)
+		}
+		epw.hostTemplates[strings.ToLower(host)] = hostTemplate
+	}
+
+	return epw, nil
+}
+
+// write writes the error response of the failed start. the status and the Retry-After header are written as is, and
+// only the body depends on the client
+func (epw *errorPageWriter) write(res http.ResponseWriter, req *http.Request, statusResult *ResourceStatusResult) {
+	retryAfter := 0
+	if statusResult.RetryAfter > 0 {
+		retryAfter = int(math.Ceil(statusResult.RetryAfter.Seconds()))
+		res.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	reason := epw.getReason(statusResult)
+
+	if acceptsHTML(req) {
+
+		// a resource still waking up is refreshed until it is ready, while a failed wake-up is refreshed only if the
+		// client was asked to retry, so that browsers do not keep refreshing on a permanent failure
+		waking := epw.isWaking(statusResult)
+		refreshAfter := retryAfter
+		if refreshAfter == 0 && waking {
+			refreshAfter = int(math.Ceil(epw.refreshInterval.Seconds()))
+		}
+
+		// rendered before writing the status, so that a broken template falls back to the JSON body
+		var page bytes.Buffer
+		if err := epw.getTemplate(req.Host).Execute(&page, errorPageData{
+			Resource:   statusResult.ResourceName,
+			Reason:     reason,
+			Waking:     waking,
+			RetryAfter: refreshAfter,
+		}); err == nil {
+			res.Header().Set("Content-Type", "text/html; charset=utf-8")
+			res.Header().Set("Cache-Control", "no-store")
+			res.WriteHeader(statusResult.Status)
+			res.Write(page.Bytes()) // nolint: errcheck
+			return
+		}
+	}
+
+	body, err := json.Marshal(resourceErrorBody{
+		Resource:   statusResult.ResourceName,
+		Reason:     reason,
+		RetryAfter: retryAfter,
+	})
+	if err != nil {
+		res.WriteHeader(statusResult.Status)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(statusResult.Status)
+	res.Write(body) // nolint: errcheck
+}
+
+func (epw *errorPageWriter) getTemplate(host string) *template.Template {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if hostTemplate, found := epw.hostTemplates[strings.ToLower(host)]; found {
+		return hostTemplate
+	}
+	return epw.defaultTemplate
+}
+
+// isWaking returns true if the resource is still waking up, rather than having failed to
+func (epw *errorPageWriter) isWaking(statusResult *ResourceStatusResult) bool {
+	return errors.Is(statusResult.Error, ErrResourceWaking) || statusResult.Status == http.StatusGatewayTimeout
+}
+
+// getReason describes the failure to the client, without the internal details of the error
+func (epw *errorPageWriter) getReason(statusResult *ResourceStatusResult) string {
+	switch {
+	case errors.Is(statusResult.Error, ErrResourceWaking):
+		return "The resource is being woken up"
+	case errors.Is(statusResult.Error, ErrRequestAborted):
+		return "The server is shutting down"
+	case errors.Is(statusResult.Error, ErrTooManyWaitingRequests):
+		return "Too many requests are waiting for the resource to wake up"
+	case errors.Is(statusResult.Error, ErrWarmResourcesBudgetExceeded):
+		return "Too many resources are awake"
+	case statusResult.Status == http.StatusGatewayTimeout:
+		return "Timed out waiting for the resource to wake up"
+	default:
+		return "Failed to wake up the resource"
+	}
+}
+
+// acceptsHTML returns true for requests of browsers, which are answered with pages rather than with JSON bodies
+func acceptsHTML(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"golang.org/x/net/http2"
+)
+
+// gRPC status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	grpcStatusDeadlineExceeded = 4
+	grpcStatusUnavailable      = 14
+)
+
+func isGRPCRequest(req *http.Request) bool {
+	return req.ProtoMajor == 2 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc")
+}
+
+// writeGRPCError writes a trailers-only gRPC response, as gRPC clients read the status of a call from its trailers
+// rather than from the http status code. a timeout is reported as DEADLINE_EXCEEDED and any other failure as
+// UNAVAILABLE, which gRPC clients may retry
+func writeGRPCError(res http.ResponseWriter, httpStatus int, message string) {
+	grpcStatus := grpcStatusUnavailable
+	if httpStatus == http.StatusGatewayTimeout {
+		grpcStatus = grpcStatusDeadlineExceeded
+	}
+
+	res.Header().Set("Content-Type", "application/grpc")
+	res.Header().Set("Grpc-Status", strconv.Itoa(grpcStatus))
+	res.Header().Set("Grpc-Message", message)
+	res.WriteHeader(http.StatusOK)
+}
+
+// protocolTransport sends gRPC requests to their targets over cleartext HTTP/2, as gRPC requires HTTP/2 end to end,
+// and all the other requests over the http transport
+type protocolTransport struct {
+	httpTransport http.RoundTripper
+	h2cTransport  http.RoundTripper
+}
+
+func newProtocolTransport(httpTransport http.RoundTripper,
+	options scalertypes.ProxyTransportOptions) *protocolTransport {
+	dialer := &net.Dialer{
+		Timeout:   options.DialTimeout.Duration,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &protocolTransport{
+		httpTransport: httpTransport,
+		h2cTransport: &http2.Transport{
+			AllowHTTP: true,
+
+			// targets are reached over cleartext, so the "TLS" dial is a plain one
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			IdleConnTimeout: options.IdleConnTimeout.Duration,
+		},
+	}
+}
+
+func (t *protocolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isGRPCRequest(req) {
+		return t.h2cTransport.RoundTrip(req)
+	}
+	return t.httpTransport.RoundTrip(req)
+}
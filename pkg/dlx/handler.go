@@ -21,11 +21,17 @@ such restriction.
 package dlx
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,6 +44,20 @@ import (
 	"k8s.io/apimachinery/pkg/util/cache"
 )
 
+// servedTargetHeader reports which target served a request routed with the failover strategy
+const servedTargetHeader = "X-Served-Target"
+
+const (
+	canaryOverrideAlways = "always"
+	canaryOverrideNever  = "never"
+)
+
+// how long clients are asked to wait before retrying requests aborted on shutdown, by when another replica should serve
+const abortedRequestRetryAfter = time.Second
+
+// proxies only hold their target, so they may be kept for long - the connections are pooled by the shared transport
+const targetProxyCacheTTL = 10 * time.Minute
+
 type Handler struct {
 	logger              logger.Logger
 	HandleFunc          func(http.ResponseWriter, *http.Request)
@@ -48,9 +68,37 @@ type Handler struct {
 	targetPort          int
 	multiTargetStrategy scalertypes.MultiTargetStrategy
 	targetURLCache      *cache.LRUExpireCache
+	transport           http.RoundTripper
+	warmUpRetryWindow   time.Duration
 	proxyLock           sync.Locker
 	lastProxyErrorTime  time.Time
 	ingressCache        ingresscache.IngressHostCacheReader
+
+	// when set, only the target selected for a request is woken up, and optionally the others in the background
+	wakeSelectedTargetOnly bool
+	lazyWakeOtherTargets   bool
+
+	stickySession       scalertypes.StickySessionOptions
+	canaryOverride      scalertypes.CanaryOverrideOptions
+	canaryHeaderPattern *regexp.Regexp
+	upgradeTracker      *upgradeTracker
+
+	// canceled when the drain deadline of a shutdown passes, to release the requests still in flight
+	abortCtx    context.Context
+	abortCancel context.CancelFunc
+
+	// set when embedded as a middleware - resolves the resources of requests instead of the ingress cache and the
+	// target name header, and serves the requests instead of proxying them to their targets
+	resourceResolver ResourceResolver
+	nextHandler      http.Handler
+
+	errorPageWriter *errorPageWriter
+
+	// how long page loads of browsers wait for their resources before they are shown the page of a resource waking up
+	// (0 to wait for the wake-up)
+	wakePageGracePeriod time.Duration
+
+	asyncWaker *asyncWaker
 }
 
 func NewHandler(parentLogger logger.Logger,
@@ -60,27 +108,115 @@ func NewHandler(parentLogger logger.Logger,
 	targetPathHeader string,
 	targetPort int,
 	multiTargetStrategy scalertypes.MultiTargetStrategy,
-	ingressCache ingresscache.IngressHostCacheReader) (Handler, error) {
+	ingressCache ingresscache.IngressHostCacheReader,
+	proxyTransportOptions scalertypes.ProxyTransportOptions,
+	proxyRetryOptions scalertypes.ProxyRetryOptions,
+	wakeSelectedTargetOnly bool,
+	lazyWakeOtherTargets bool,
+	stickySessionOptions scalertypes.StickySessionOptions,
+	canaryOverrideOptions scalertypes.CanaryOverrideOptions,
+	enableHTTP2 bool,
+	errorPageOptions scalertypes.ErrorPageOptions,
+	asyncWakeOptions scalertypes.AsyncWakeOptions) (Handler, error) {
+	childLogger := parentLogger.GetChild("handler")
+	if multiTargetStrategy == scalertypes.MultiTargetStrategySticky {
+		if err := validateStickySessionOptions(stickySessionOptions); err != nil {
+			return Handler{}, errors.Wrap(err, "Invalid sticky session options")
+		}
+	}
+
+	var transport http.RoundTripper = newProxyTransport(proxyTransportOptions)
+	if enableHTTP2 {
+		transport = newProtocolTransport(transport, proxyTransportOptions)
+	}
+	if proxyRetryOptions.WarmUpWindow.Duration > 0 {
+		transport = newWarmUpRetryTransport(childLogger, transport, proxyRetryOptions)
+	}
+
+	abortCtx, abortCancel := context.WithCancel(context.Background())
 	h := Handler{
-		logger:              parentLogger.GetChild("handler"),
-		resourceStarter:     resourceStarter,
-		resourceScaler:      resourceScaler,
-		targetNameHeader:    targetNameHeader,
-		targetPathHeader:    targetPathHeader,
-		targetPort:          targetPort,
-		multiTargetStrategy: multiTargetStrategy,
-		targetURLCache:      cache.NewLRUExpireCache(100),
-		proxyLock:           &sync.Mutex{},
-		lastProxyErrorTime:  time.Now(),
-		ingressCache:        ingressCache,
+		logger:                 childLogger,
+		resourceStarter:        resourceStarter,
+		resourceScaler:         resourceScaler,
+		targetNameHeader:       targetNameHeader,
+		targetPathHeader:       targetPathHeader,
+		targetPort:             targetPort,
+		multiTargetStrategy:    multiTargetStrategy,
+		targetURLCache:         cache.NewLRUExpireCache(100),
+		transport:              transport,
+		warmUpRetryWindow:      proxyRetryOptions.WarmUpWindow.Duration,
+		proxyLock:              &sync.Mutex{},
+		lastProxyErrorTime:     time.Now(),
+		ingressCache:           ingressCache,
+		wakeSelectedTargetOnly: wakeSelectedTargetOnly,
+		lazyWakeOtherTargets:   lazyWakeOtherTargets,
+		stickySession:          stickySessionOptions,
+		canaryOverride:         canaryOverrideOptions,
+		upgradeTracker:         newUpgradeTracker(),
+		abortCtx:               abortCtx,
+		abortCancel:            abortCancel,
+	}
+	if canaryOverrideOptions.HeaderPattern != "" {
+		canaryHeaderPattern, err := regexp.Compile(canaryOverrideOptions.HeaderPattern)
+		if err != nil {
+			return Handler{}, errors.Wrap(err, "Failed to compile canary header pattern")
+		}
+		h.canaryHeaderPattern = canaryHeaderPattern
+	}
+	errorPageWriter, err := newErrorPageWriter(errorPageOptions)
+	if err != nil {
+		return Handler{}, errors.Wrap(err, "Failed to create error page writer")
+	}
+	h.errorPageWriter = errorPageWriter
+	h.wakePageGracePeriod = errorPageOptions.WakePageGracePeriod.Duration
+	// without the readiness cache every request would look like it wakes its resource, so the requests to ready
+	// resources would be answered without ever being served
+	if len(asyncWakeOptions.Routes) > 0 && resourceStarter.readinessCache == nil {
+		return Handler{}, errors.New("Async wake routes require the readiness cache")
 	}
+	asyncWaker, err := newAsyncWaker(asyncWakeOptions)
+	if err != nil {
+		return Handler{}, errors.Wrap(err, "Failed to create async waker")
+	}
+	h.asyncWaker = asyncWaker
 	h.HandleFunc = h.handleRequest
 	return h, nil
 }
 
+// newProxyTransport creates the transport shared by all the reverse proxies, based on the default transport
+func newProxyTransport(options scalertypes.ProxyTransportOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.MaxIdleConns > 0 {
+		transport.MaxIdleConns = options.MaxIdleConns
+	}
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	}
+	if options.IdleConnTimeout.Duration > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout.Duration
+	}
+	if options.DialTimeout.Duration > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   options.DialTimeout.Duration,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if options.TLSHandshakeTimeout.Duration > 0 {
+		transport.TLSHandshakeTimeout = options.TLSHandshakeTimeout.Duration
+	}
+	transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout.Duration
+	return transport
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	h.handleRequest(res, req)
+}
+
 func (h *Handler) handleRequest(res http.ResponseWriter, req *http.Request) {
 	var err error
 	var resourceNames []string
+	var resourceWeights []int
 
 	// first try to see if our request came from ingress controller
 	forwardedHost := req.Header.Get("X-Forwarded-Host")
@@ -99,7 +235,7 @@ func (h *Handler) handleRequest(res http.ResponseWriter, req *http.Request) {
 		resourceNames = append(resourceNames, resourceName)
 		resourceTargetURLMap[resourceName] = targetURL
 	} else {
-		resourceNames, err = h.getResourceNames(req)
+		resourceNames, resourceWeights, err = h.getResourceNames(req)
 		if err != nil {
 			h.logger.WarnWith("Failed to get resource names and path from request",
 				"error", err.Error(),
@@ -122,57 +258,171 @@ func (h *Handler) handleRequest(res http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	statusResult := h.startResources(resourceNames)
-
-	if statusResult != nil && statusResult.Error != nil {
-		res.WriteHeader(statusResult.Status)
+	// the requests to async routes are answered before they are served only when their resources need to wake up
+	if asyncRoute := h.asyncWaker.getRoute(req); asyncRoute != nil && !h.areResourcesReady(resourceNames) {
+		h.handleAsyncRequest(res, req, asyncRoute, resourceNames, resourceWeights, resourceTargetURLMap)
 		return
 	}
 
-	targetURL, err := h.selectTargetURL(resourceNames, resourceTargetURLMap)
-	if err != nil {
-		res.WriteHeader(http.StatusInternalServerError)
-		return
+	// page loads of browsers are not left waiting for the resources to wake up, as the resources keep waking up in the
+	// background. other requests would not be resent by the refresh of the page, so they wait for the wake-up
+	var wakeWaitDone <-chan struct{}
+	if h.shouldShowWakePage(req) {
+		wakeWaitCtx, cancelWakeWait := context.WithTimeout(context.Background(), h.wakePageGracePeriod)
+		defer cancelWakeWait()
+		wakeWaitDone = wakeWaitCtx.Done()
 	}
 
-	h.proxyLock.Lock()
-	targetURLCacheKey := targetURL.String()
+	// a request forced to a target is not failed over to the other targets
+	_, canaryOverridden := h.getCanaryOverrideResourceName(req, resourceNames)
+	if h.multiTargetStrategy == scalertypes.MultiTargetStrategyFailover && len(resourceNames) > 1 && !canaryOverridden {
+		servedResourceName, statusResult := h.startFailoverResources(resourceNames, wakeWaitDone)
+		if statusResult != nil {
+			h.writeResourceStartError(res, req, statusResult)
+			return
+		}
 
-	// if in cache, do not log to avoid multiple identical log lines.
-	if _, found := h.targetURLCache.Get(targetURLCacheKey); !found {
-		h.logger.DebugWith("Creating reverse proxy", "targetURLCache", targetURL)
+		res.Header().Set(servedTargetHeader, servedResourceName)
+		h.serveRequest(res, req, resourceTargetURLMap[servedResourceName], servedResourceName)
+		return
 	}
 
-	// store in cache
-	h.targetURLCache.Add(targetURLCacheKey, true, 5*time.Second)
-	h.proxyLock.Unlock()
+	// the target is selected before the resources are started only if just the selected target should be woken
+	var targetURL *url.URL
+	resourceNamesToStart := resourceNames
+	if h.wakeSelectedTargetOnly && len(resourceNames) > 1 {
+		if targetURL, err = h.selectTargetURL(req, resourceNames, resourceWeights, resourceTargetURLMap); err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resourceNamesToStart = []string{h.getTargetResourceName(targetURL, resourceTargetURLMap)}
+		if h.lazyWakeOtherTargets {
+			h.startOtherResourcesInBackground(resourceNames, resourceNamesToStart[0])
+		}
+	}
 
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	if statusResult := h.startResourcesUntil(resourceNamesToStart, wakeWaitDone); statusResult != nil &&
+		statusResult.Error != nil {
+		h.writeResourceStartError(res, req, statusResult)
+		return
+	}
 
-	// override the proxy's error handler in order to make the "context canceled" log appear once every hour at most,
-	// because it occurs frequently and spams the logs file, but we didn't want to remove it entirely.
-	proxy.ErrorHandler = func(rw http.ResponseWriter, _ *http.Request, err error) {
-		if err == nil {
+	if targetURL == nil {
+		if targetURL, err = h.selectTargetURL(req, resourceNames, resourceWeights, resourceTargetURLMap); err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		timeSinceLastCtxErr := time.Since(h.lastProxyErrorTime).Hours() > 1
-		if strings.Contains(err.Error(), "context canceled") && timeSinceLastCtxErr {
-			h.lastProxyErrorTime = time.Now()
+	}
+
+	targetResourceName := h.getTargetResourceName(targetURL, resourceTargetURLMap)
+	if h.multiTargetStrategy == scalertypes.MultiTargetStrategySticky && len(resourceNames) > 1 && !canaryOverridden {
+		h.setAffinityCookie(res, targetResourceName)
+	}
+
+	h.serveRequest(res, req, targetURL, targetResourceName)
+}
+
+// shouldShowWakePage returns whether the request is a page load of a browser, which is shown the page of a resource
+// waking up once the wake page grace period passes
+func (h *Handler) shouldShowWakePage(req *http.Request) bool {
+	if h.wakePageGracePeriod <= 0 {
+		return false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return acceptsHTML(req) && !isGRPCRequest(req)
+}
+
+// serveRequest proxies the request to its target, or when embedded as a middleware in front of a next handler, passes
+// it on to the next handler along with the target
+func (h *Handler) serveRequest(res http.ResponseWriter, req *http.Request, targetURL *url.URL, resourceName string) {
+	if h.nextHandler == nil {
+		h.proxyRequest(res, req, targetURL, resourceName)
+		return
+	}
+
+	h.nextHandler.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), targetContextKey{}, Target{
+		ResourceName: resourceName,
+		URL:          targetURL,
+	})))
+}
+
+func (h *Handler) proxyRequest(res http.ResponseWriter, req *http.Request, targetURL *url.URL, resourceName string) {
+	proxy := h.getOrCreateProxy(targetURL, resourceName)
+
+	// the proxy hijacks the connection once the target switches protocols, and keeps serving it after the proxy is
+	// evicted from the cache, as it is referenced until the connection is closed
+	if isUpgradeRequest(req) {
+		h.logger.DebugWith("Proxying upgrade request",
+			"resourceName", resourceName,
+			"upgrade", req.Header.Get("Upgrade"))
+		res = &upgradeResponseWriter{ResponseWriter: res, tracker: h.upgradeTracker}
+	}
+
+	// a request still proxied when the requests are aborted is canceled, which also closes upgraded connections
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	stopAbortPropagation := context.AfterFunc(h.abortCtx, cancel)
+	defer stopAbortPropagation()
+
+	proxy.ServeHTTP(res, h.withWarmUpRetryDeadline(withProxyTargetURL(req.WithContext(ctx), targetURL), resourceName))
+}
+
+// abortRequests releases the requests waiting for their resources to start with a 503, and cancels the requests
+// being proxied. it is called on shutdown, once the in-flight requests were not drained in time
+func (h *Handler) abortRequests() {
+	h.abortCancel()
+}
+
+// waitForResourceStart waits for the result of starting the resource, unless the requests are aborted or the wait is
+// done first (a nil wait channel waits for the result)
+func (h *Handler) waitForResourceStart(resourceName string,
+	responseChan chan ResourceStatusResult,
+	waitDone <-chan struct{}) ResourceStatusResult {
+	select {
+	case statusResult := <-responseChan:
+		return statusResult
+	case <-waitDone:
+		return ResourceStatusResult{
+			ResourceName: resourceName,
+			Status:       http.StatusServiceUnavailable,
+			Error:        ErrResourceWaking,
 		}
-		if !strings.Contains(err.Error(), "context canceled") || timeSinceLastCtxErr {
-			h.logger.DebugWith("http: proxy error", "error", err)
+	case <-h.abortCtx.Done():
+		return ResourceStatusResult{
+			ResourceName: resourceName,
+			Status:       http.StatusServiceUnavailable,
+			Error:        ErrRequestAborted,
+			RetryAfter:   abortedRequestRetryAfter,
 		}
-		rw.WriteHeader(http.StatusBadGateway)
 	}
+}
 
-	proxy.ServeHTTP(res, req)
+func (h *Handler) writeResourceStartError(res http.ResponseWriter,
+	req *http.Request,
+	statusResult *ResourceStatusResult) {
+	if isGRPCRequest(req) {
+		if statusResult.RetryAfter > 0 {
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(statusResult.RetryAfter.Seconds()))))
+		}
+		writeGRPCError(res, statusResult.Status, fmt.Sprintf("Failed to start resource %s", statusResult.ResourceName))
+		return
+	}
+	h.errorPageWriter.write(res, req, statusResult)
 }
 
-func (h *Handler) getResourceNames(req *http.Request) ([]string, error) {
+// getResourceNames returns the names of the resources the request targets, along with their routing weights (nil when
+// the targets are not weighted)
+func (h *Handler) getResourceNames(req *http.Request) ([]string, []int, error) {
+	if h.resourceResolver != nil {
+		return h.resolveResourceNames(req)
+	}
+
 	// first try to get the resource names from the ingress cache
-	resourceNames, err := h.getValuesFromCache(req)
+	resourceNames, resourceWeights, err := h.getValuesFromCache(req)
 	if err == nil {
-		return resourceNames, nil
+		return resourceNames, resourceWeights, nil
 	}
 
 	h.logger.DebugWith("Failed to get resource names from ingress cache, trying to extract from the request headers",
@@ -183,25 +433,60 @@ func (h *Handler) getResourceNames(req *http.Request) ([]string, error) {
 	// old implementation for backward compatibility
 	targetNameHeaderValue := req.Header.Get(h.targetNameHeader)
 	if targetNameHeaderValue == "" {
-		return nil, errors.New("No target name header found")
+		return nil, nil, errors.New("No target name header found")
 	}
 	resourceNames = strings.Split(targetNameHeaderValue, ",")
-	return resourceNames, nil
+	return resourceNames, nil, nil
+}
+
+// resolveResourceNames returns the resources the resource resolver resolves for the request. weights that can't be
+// routed by are ignored, as with the weights of the ingresses
+func (h *Handler) resolveResourceNames(req *http.Request) ([]string, []int, error) {
+	resourceNames, resourceWeights, err := h.resourceResolver(req)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to resolve resource names")
+	}
+	if len(resourceNames) == 0 {
+		return nil, nil, errors.New("No resources resolved")
+	}
+
+	if resourceWeights != nil {
+		if err := ingresscache.ValidateTargetWeights(resourceNames, resourceWeights); err != nil {
+			h.logger.WarnWith("Invalid resolved resource weights, ignoring weights",
+				"resourceNames", resourceNames,
+				"resourceWeights", resourceWeights,
+				"error", err.Error())
+			return resourceNames, nil, nil
+		}
+	}
+
+	return resourceNames, resourceWeights, nil
 }
 
-func (h *Handler) getValuesFromCache(req *http.Request) ([]string, error) {
+func (h *Handler) getValuesFromCache(req *http.Request) ([]string, []int, error) {
+	if h.ingressCache == nil {
+		return nil, nil, errors.New("No ingress cache")
+	}
+
 	host := req.Host
 	path := h.getRequestURLPath(req)
-	resourceNames, err := h.ingressCache.Get(host, path)
+	var resourceNames []string
+	var resourceWeights []int
+	var err error
+	if weightedIngressCache, ok := h.ingressCache.(ingresscache.WeightedIngressHostCacheReader); ok {
+		resourceNames, resourceWeights, err = weightedIngressCache.GetWeighted(host, path)
+	} else {
+		resourceNames, err = h.ingressCache.Get(host, path)
+	}
 	if err != nil {
-		return nil, errors.New("Failed to get resource names from ingress cache")
+		return nil, nil, errors.New("Failed to get resource names from ingress cache")
 	}
 
 	if len(resourceNames) == 0 {
-		return nil, errors.New("No resources found in ingress cache")
+		return nil, nil, errors.New("No resources found in ingress cache")
 	}
 
-	return resourceNames, nil
+	return resourceNames, resourceWeights, nil
 }
 
 func (h *Handler) parseTargetURL(resourceName, path string) (*url.URL, int) {
@@ -219,22 +504,32 @@ func (h *Handler) parseTargetURL(resourceName, path string) (*url.URL, int) {
 }
 
 func (h *Handler) startResources(resourceNames []string) *ResourceStatusResult {
+	return h.startResourcesUntil(resourceNames, nil)
+}
+
+// startResourcesUntil is like startResources, but stops waiting for the resources once the wait is done, leaving them
+// to start in the background
+func (h *Handler) startResourcesUntil(resourceNames []string, waitDone <-chan struct{}) *ResourceStatusResult {
+	// the channel is not closed, as it is buffered for all the results and some may arrive after an early return
 	responseChan := make(chan ResourceStatusResult, len(resourceNames))
-	defer close(responseChan)
 
 	// Start all resources in separate go routines
 	for _, resourceName := range resourceNames {
-		go h.resourceStarter.handleResourceStart(resourceName, responseChan)
+		go h.resourceStarter.handleResourceStartUntil(resourceName, responseChan, waitDone)
 	}
 
 	// Wait for all resources to finish starting
-	for range resourceNames {
-		statusResult := <-responseChan
+	for _, resourceName := range resourceNames {
+		statusResult := h.waitForResourceStart(resourceName, responseChan, waitDone)
 
 		if statusResult.Error != nil {
-			h.logger.WarnWith("Failed to start resource",
-				"resource", statusResult.ResourceName,
-				"err", errors.GetErrorStackString(statusResult.Error, 10))
+
+			// a resource still waking up did not fail, it is just no longer waited for
+			if !errors.Is(statusResult.Error, ErrResourceWaking) {
+				h.logger.WarnWith("Failed to start resource",
+					"resource", statusResult.ResourceName,
+					"err", errors.GetErrorStackString(statusResult.Error, 10))
+			}
 			return &statusResult
 		}
 	}
@@ -242,22 +537,105 @@ func (h *Handler) startResources(resourceNames []string) *ResourceStatusResult {
 	return nil
 }
 
-func (h *Handler) selectTargetURL(resourceNames []string, resourceTargetURLMap map[string]*url.URL) (*url.URL, error) {
+// startFailoverResources returns the first of the resources, in order, that started successfully. unless only the
+// selected target should be woken, all the resources are started in parallel so that failing over takes no longer
+// than starting the resource failed over to. if all the resources fail to start, the last failure is returned
+func (h *Handler) startFailoverResources(resourceNames []string, waitDone <-chan struct{}) (string, *ResourceStatusResult) {
+	var statusResult *ResourceStatusResult
+	if h.wakeSelectedTargetOnly {
+		for _, resourceName := range resourceNames {
+			if statusResult = h.startResourcesUntil([]string{resourceName}, waitDone); statusResult == nil {
+				return resourceName, nil
+			}
+
+			// a resource still waking up is not failed over
+			if errors.Is(statusResult.Error, ErrResourceWaking) {
+				return "", statusResult
+			}
+		}
+		return "", statusResult
+	}
+
+	responseChannels := make([]chan ResourceStatusResult, len(resourceNames))
+	for resourceIndex, resourceName := range resourceNames {
+		responseChannels[resourceIndex] = make(chan ResourceStatusResult, 1)
+		go h.resourceStarter.handleResourceStartUntil(resourceName, responseChannels[resourceIndex], waitDone)
+	}
+
+	for resourceIndex, resourceName := range resourceNames {
+		result := h.waitForResourceStart(resourceName, responseChannels[resourceIndex], waitDone)
+		if result.Error == nil {
+			return resourceName, nil
+		}
+
+		// aborted requests, and resources still waking up, are not failed over
+		if h.abortCtx.Err() != nil || errors.Is(result.Error, ErrResourceWaking) {
+			return "", &result
+		}
+
+		h.logger.WarnWith("Failed to start resource, failing over to the next target",
+			"resource", resourceName,
+			"err", errors.GetErrorStackString(result.Error, 10))
+		statusResult = &result
+	}
+
+	return "", statusResult
+}
+
+func (h *Handler) startOtherResourcesInBackground(resourceNames []string, selectedResourceName string) {
+	for _, resourceName := range resourceNames {
+		if resourceName != selectedResourceName {
+			go h.resourceStarter.handleBackgroundResourceStart(resourceName)
+		}
+	}
+}
+
+// areResourcesReady returns whether all the resources are known to be ready by the readiness cache
+func (h *Handler) areResourcesReady(resourceNames []string) bool {
+	for _, resourceName := range resourceNames {
+		if !h.resourceStarter.isResourceReady(resourceName) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectTargetURL selects the target URL to proxy the request to according to the canary override rules, or if none
+// applies, according to the multi target strategy. weighted targets are selected at random according to their
+// weights, unless the strategy is primary or sticky
+func (h *Handler) selectTargetURL(req *http.Request,
+	resourceNames []string,
+	resourceWeights []int,
+	resourceTargetURLMap map[string]*url.URL) (*url.URL, error) {
 	if len(resourceNames) == 1 {
 		return resourceTargetURLMap[resourceNames[0]], nil
-	} else if len(resourceNames) != 2 {
+	} else if len(resourceNames) == 0 {
 		h.logger.WarnWith("Unsupported amount of targets",
 			"targetsAmount", len(resourceNames))
 		return nil, errors.Errorf("Unsupported amount of targets: %d", len(resourceNames))
 	}
 
+	if resourceName, overridden := h.getCanaryOverrideResourceName(req, resourceNames); overridden {
+		return resourceTargetURLMap[resourceName], nil
+	}
+
 	switch h.multiTargetStrategy {
 	case scalertypes.MultiTargetStrategyRandom:
+		if resourceWeights != nil {
+			return resourceTargetURLMap[h.selectWeightedResourceName(resourceNames, resourceWeights)], nil
+		}
 		return resourceTargetURLMap[resourceNames[rand.Intn(len(resourceNames))]], nil
-	case scalertypes.MultiTargetStrategyPrimary:
+	case scalertypes.MultiTargetStrategyPrimary, scalertypes.MultiTargetStrategyFailover:
 		return resourceTargetURLMap[resourceNames[0]], nil
 	case scalertypes.MultiTargetStrategyCanary:
-		return resourceTargetURLMap[resourceNames[1]], nil
+
+		// without weights, all the traffic goes to the canary - the last target
+		if resourceWeights != nil {
+			return resourceTargetURLMap[h.selectWeightedResourceName(resourceNames, resourceWeights)], nil
+		}
+		return resourceTargetURLMap[resourceNames[len(resourceNames)-1]], nil
+	case scalertypes.MultiTargetStrategySticky:
+		return resourceTargetURLMap[h.selectStickyResourceName(req, resourceNames, resourceWeights)], nil
 	default:
 		h.logger.WarnWith("Unsupported multi target strategy",
 			"strategy", h.multiTargetStrategy)
@@ -265,6 +643,266 @@ func (h *Handler) selectTargetURL(resourceNames []string, resourceTargetURLMap m
 	}
 }
 
+// getCanaryOverrideResourceName returns the target the request is forced to by the canary override header or cookie,
+// if any. same as nginx, a header that does not match is ignored and the cookie is evaluated next
+func (h *Handler) getCanaryOverrideResourceName(req *http.Request, resourceNames []string) (string, bool) {
+	if len(resourceNames) < 2 {
+		return "", false
+	}
+
+	primaryResourceName := resourceNames[0]
+	canaryResourceName := resourceNames[len(resourceNames)-1]
+
+	if h.canaryOverride.Header != "" {
+		if headerValue := req.Header.Get(h.canaryOverride.Header); headerValue != "" {
+			switch {
+			case h.canaryOverride.HeaderValue != "":
+				if headerValue == h.canaryOverride.HeaderValue {
+					return canaryResourceName, true
+				}
+			case h.canaryHeaderPattern != nil:
+				if h.canaryHeaderPattern.MatchString(headerValue) {
+					return canaryResourceName, true
+				}
+			case headerValue == canaryOverrideAlways:
+				return canaryResourceName, true
+			case headerValue == canaryOverrideNever:
+				return primaryResourceName, true
+			}
+		}
+	}
+
+	if h.canaryOverride.Cookie != "" {
+		if cookie, err := req.Cookie(h.canaryOverride.Cookie); err == nil {
+			switch cookie.Value {
+			case canaryOverrideAlways:
+				return canaryResourceName, true
+			case canaryOverrideNever:
+				return primaryResourceName, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func (h *Handler) selectWeightedResourceName(resourceNames []string, resourceWeights []int) string {
+	return h.getResourceNameByWeight(resourceNames, resourceWeights, rand.Intn(h.getTotalWeight(resourceWeights)))
+}
+
+// selectStickyResourceName selects the target pinned by the affinity cookie if it is still a target of the route,
+// otherwise the target the session key hashes to. requests without a session key are routed as with the random
+// strategy
+func (h *Handler) selectStickyResourceName(req *http.Request, resourceNames []string, resourceWeights []int) string {
+	if h.stickySession.AffinityCookieName != "" {
+		if cookie, err := req.Cookie(h.stickySession.AffinityCookieName); err == nil {
+			for _, resourceName := range resourceNames {
+				if resourceName == cookie.Value {
+					return resourceName
+				}
+			}
+		}
+	}
+
+	sessionKey := h.getStickySessionKey(req)
+	if sessionKey == "" {
+		if resourceWeights != nil {
+			return h.selectWeightedResourceName(resourceNames, resourceWeights)
+		}
+		return resourceNames[rand.Intn(len(resourceNames))]
+	}
+
+	sessionHash := fnv.New32a()
+	sessionHash.Write([]byte(sessionKey)) // nolint: errcheck
+	if resourceWeights != nil {
+		return h.getResourceNameByWeight(resourceNames,
+			resourceWeights,
+			int(sessionHash.Sum32()%uint32(h.getTotalWeight(resourceWeights))))
+	}
+	return resourceNames[sessionHash.Sum32()%uint32(len(resourceNames))]
+}
+
+func (h *Handler) getStickySessionKey(req *http.Request) string {
+	switch h.stickySession.KeySource {
+	case scalertypes.StickySessionKeySourceCookie:
+		cookie, err := req.Cookie(h.stickySession.KeyName)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	case scalertypes.StickySessionKeySourceHeader:
+		return req.Header.Get(h.stickySession.KeyName)
+	default:
+
+		// behind an ingress controller the remote address is the controller's, so prefer the forwarded client address.
+		// clients may send their own forwarded addresses, so only the last one - added by the ingress - is trusted
+		if forwardedFor := req.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+			lastForwardedFor := forwardedFor[len(forwardedFor)-1]
+			if clientIP := strings.TrimSpace(lastForwardedFor[strings.LastIndex(lastForwardedFor, ",")+1:]); clientIP != "" {
+				return clientIP
+			}
+		}
+		if realIP := req.Header.Get("X-Real-Ip"); realIP != "" {
+			return realIP
+		}
+		if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			return clientIP
+		}
+		return req.RemoteAddr
+	}
+}
+
+// validateStickySessionOptions fails on options that would silently route sessions by another key, or at random
+func validateStickySessionOptions(stickySessionOptions scalertypes.StickySessionOptions) error {
+	switch stickySessionOptions.KeySource {
+	case "", scalertypes.StickySessionKeySourceClientIP:
+		return nil
+	case scalertypes.StickySessionKeySourceCookie, scalertypes.StickySessionKeySourceHeader:
+		if stickySessionOptions.KeyName == "" {
+			return errors.Errorf("Key name is required with the %s key source", stickySessionOptions.KeySource)
+		}
+		return nil
+	default:
+		return errors.Errorf("Unknown key source: %s", stickySessionOptions.KeySource)
+	}
+}
+
+func (h *Handler) setAffinityCookie(res http.ResponseWriter, resourceName string) {
+	if h.stickySession.AffinityCookieName == "" || resourceName == "" {
+		return
+	}
+
+	http.SetCookie(res, &http.Cookie{
+		Name:     h.stickySession.AffinityCookieName,
+		Value:    resourceName,
+		Path:     "/",
+		MaxAge:   int(h.stickySession.AffinityCookieMaxAge.Seconds()),
+		HttpOnly: true,
+	})
+}
+
+func (h *Handler) getTotalWeight(resourceWeights []int) int {
+	totalWeight := 0
+	for _, weight := range resourceWeights {
+		totalWeight += weight
+	}
+	return totalWeight
+}
+
+// getResourceNameByWeight returns the resource whose weight range holds the selected weight
+func (h *Handler) getResourceNameByWeight(resourceNames []string, resourceWeights []int, selectedWeight int) string {
+	for resourceIndex, weight := range resourceWeights {
+		if selectedWeight < weight {
+			return resourceNames[resourceIndex]
+		}
+		selectedWeight -= weight
+	}
+
+	// unreachable, as the weights of both the ingress cache and the resource resolver are validated
+	return resourceNames[len(resourceNames)-1]
+}
+
+// getOrCreateProxy returns the reverse proxy to the host of the target URL, creating it if it is not cached yet. the
+// proxy is shared by all the paths of the target, so requests are passed to it along with their target URL through
+// withProxyTargetURL
+func (h *Handler) getOrCreateProxy(targetURL *url.URL, resourceName string) *httputil.ReverseProxy {
+	h.proxyLock.Lock()
+	defer h.proxyLock.Unlock()
+
+	targetURLCacheKey := fmt.Sprintf("%s@%s://%s", resourceName, targetURL.Scheme, targetURL.Host)
+	if proxy, found := h.targetURLCache.Get(targetURLCacheKey); found {
+		return proxy.(*httputil.ReverseProxy)
+	}
+
+	h.logger.DebugWith("Creating reverse proxy", "targetURL", targetURL, "resourceName", resourceName)
+	proxy := &httputil.ReverseProxy{
+		Director: func(outReq *http.Request) {
+
+			// rewrites the request the way a single host proxy to its target URL would, joining the target path
+			requestTargetURL, _ := outReq.Context().Value(proxyTargetURLContextKey{}).(*url.URL)
+			if requestTargetURL == nil {
+				requestTargetURL = targetURL
+			}
+			httputil.NewSingleHostReverseProxy(requestTargetURL).Director(outReq)
+		},
+		Transport: h.transport,
+	}
+
+	// override the proxy's error handler in order to make the "context canceled" log appear once every hour at most,
+	// because it occurs frequently and spams the logs file, but we didn't want to remove it entirely.
+	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		if err == nil {
+			return
+		}
+		timeSinceLastCtxErr := time.Since(h.lastProxyErrorTime).Hours() > 1
+		if strings.Contains(err.Error(), "context canceled") && timeSinceLastCtxErr {
+			h.lastProxyErrorTime = time.Now()
+		}
+		if !strings.Contains(err.Error(), "context canceled") || timeSinceLastCtxErr {
+			h.logger.DebugWith("http: proxy error", "error", err)
+		}
+
+		// requests canceled by the abort are answered as unavailable, as their target was not at fault
+		if h.abortCtx.Err() != nil {
+			if isGRPCRequest(req) {
+				writeGRPCError(rw, http.StatusServiceUnavailable, "Aborted proxying the request, as the DLX is shutting down")
+				return
+			}
+			rw.Header().Set("Retry-After", strconv.Itoa(int(abortedRequestRetryAfter.Seconds())))
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		// the resource may have been scaled to zero since it was cached as ready
+		if !strings.Contains(err.Error(), "context canceled") {
+			h.resourceStarter.invalidateResourceReadiness(resourceName)
+		}
+		if isGRPCRequest(req) {
+			writeGRPCError(rw, http.StatusBadGateway, fmt.Sprintf("Failed to reach resource %s", resourceName))
+			return
+		}
+		rw.WriteHeader(http.StatusBadGateway)
+	}
+
+	h.targetURLCache.Add(targetURLCacheKey, proxy, targetProxyCacheTTL)
+	return proxy
+}
+
+type proxyTargetURLContextKey struct{}
+
+// withProxyTargetURL passes the target URL of the request, path included, to the proxy of its target
+func withProxyTargetURL(req *http.Request, targetURL *url.URL) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), proxyTargetURLContextKey{}, targetURL))
+}
+
+// withWarmUpRetryDeadline allows retrying the request if the target resource was woken up within the warm-up window
+func (h *Handler) withWarmUpRetryDeadline(req *http.Request, resourceName string) *http.Request {
+	if h.warmUpRetryWindow == 0 {
+		return req
+	}
+
+	wakeEndTime := h.resourceStarter.getWakeEndTime(resourceName)
+	if wakeEndTime.IsZero() {
+		return req
+	}
+
+	deadline := wakeEndTime.Add(h.warmUpRetryWindow)
+	if time.Now().After(deadline) {
+		return req
+	}
+
+	return withRetryDeadline(req, deadline)
+}
+
+func (h *Handler) getTargetResourceName(targetURL *url.URL, resourceTargetURLMap map[string]*url.URL) string {
+	for resourceName, resourceTargetURL := range resourceTargetURLMap {
+		if resourceTargetURL == targetURL {
+			return resourceName
+		}
+	}
+	return ""
+}
+
 func (h *Handler) URLBadParse(resourceName string, err error) int {
 	h.logger.Warn("Failed to parse url for resource",
 		"resourceName", resourceName,
@@ -1,11 +1,23 @@
 package dlx
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -18,6 +30,8 @@ import (
 	nucliozap "github.com/nuclio/zap"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type HandlerTestSuite struct {
@@ -178,6 +192,1174 @@ func (suite *HandlerTestSuite) TestHandleRequest() {
 	}
 }
 
+func (suite *HandlerTestSuite) TestProxyReuse() {
+	testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+	suite.Require().NoError(err)
+
+	firstURL, err := url.Parse(fmt.Sprintf("http://%s:%d/test/path", suite.backendHost, suite.backendPort))
+	suite.Require().NoError(err)
+	secondURL, err := url.Parse(fmt.Sprintf("http://%s:%d/test/path/to/multiple", suite.backendHost, suite.backendPort))
+	suite.Require().NoError(err)
+
+	// all the paths of a target are served by the same proxy
+	firstProxy := testHandler.getOrCreateProxy(firstURL, "test-targets-name-1")
+	suite.Require().Same(firstProxy, testHandler.getOrCreateProxy(firstURL, "test-targets-name-1"))
+	suite.Require().Same(firstProxy, testHandler.getOrCreateProxy(secondURL, "test-targets-name-1"))
+	suite.Require().Len(testHandler.targetURLCache.Keys(), 1)
+
+	// a different target gets its own proxy, sharing the transport
+	secondProxy := testHandler.getOrCreateProxy(secondURL, "test-targets-name-2")
+	suite.Require().NotSame(firstProxy, secondProxy)
+	suite.Require().Same(firstProxy.Transport, secondProxy.Transport)
+
+	// the shared proxy directs each request to its own target path
+	for _, targetURL := range []*url.URL{firstURL, secondURL} {
+		outReq := withProxyTargetURL(httptest.NewRequest("GET", "/", nil), targetURL)
+		firstProxy.Director(outReq)
+		suite.Require().Equal(targetURL.Host, outReq.URL.Host)
+		suite.Require().Equal(targetURL.Path+"/", outReq.URL.Path)
+	}
+}
+
+func (suite *HandlerTestSuite) TestNewProxyTransport() {
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+
+	// zero options fall back to the defaults
+	transport := newProxyTransport(scalertypes.ProxyTransportOptions{})
+	suite.Require().Equal(defaultTransport.MaxIdleConns, transport.MaxIdleConns)
+	suite.Require().Equal(defaultTransport.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	suite.Require().Equal(defaultTransport.IdleConnTimeout, transport.IdleConnTimeout)
+	suite.Require().Equal(defaultTransport.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	suite.Require().Zero(transport.ResponseHeaderTimeout)
+
+	transport = newProxyTransport(scalertypes.ProxyTransportOptions{
+		MaxIdleConns:          500,
+		MaxIdleConnsPerHost:   50,
+		IdleConnTimeout:       scalertypes.Duration{Duration: time.Minute},
+		DialTimeout:           scalertypes.Duration{Duration: 5 * time.Second},
+		TLSHandshakeTimeout:   scalertypes.Duration{Duration: 3 * time.Second},
+		ResponseHeaderTimeout: scalertypes.Duration{Duration: 30 * time.Second},
+	})
+	suite.Require().Equal(500, transport.MaxIdleConns)
+	suite.Require().Equal(50, transport.MaxIdleConnsPerHost)
+	suite.Require().Equal(time.Minute, transport.IdleConnTimeout)
+	suite.Require().Equal(3*time.Second, transport.TLSHandshakeTimeout)
+	suite.Require().Equal(30*time.Second, transport.ResponseHeaderTimeout)
+	suite.Require().NotSame(defaultTransport, transport)
+}
+
+func (suite *HandlerTestSuite) TestWarmUpRetryTransport() {
+	connectionRefusedErr := &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	connectionResetErr := &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	for _, testCase := range []struct {
+		name             string
+		method           string
+		body             string
+		headers          map[string]string
+		retryDeadline    time.Duration
+		attemptErrors    []error
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{
+			name:             "Connection refused is retried with the same body",
+			method:           http.MethodPost,
+			body:             "test-body",
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{connectionRefusedErr, connectionRefusedErr},
+			expectedAttempts: 3,
+		}, {
+			name:             "Connection reset is retried for idempotent requests",
+			method:           http.MethodGet,
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{connectionResetErr},
+			expectedAttempts: 2,
+		}, {
+			name:             "Connection reset is retried for requests with an idempotency key",
+			method:           http.MethodPost,
+			body:             "test-body",
+			headers:          map[string]string{"Idempotency-Key": "test-key"},
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{connectionResetErr},
+			expectedAttempts: 2,
+		}, {
+			name:             "Connection reset is not retried for non-idempotent requests",
+			method:           http.MethodPost,
+			body:             "test-body",
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{connectionResetErr},
+			expectedAttempts: 1,
+			expectErr:        true,
+		}, {
+			name:             "Other errors are not retried",
+			method:           http.MethodGet,
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{errors.New("test error")},
+			expectedAttempts: 1,
+			expectErr:        true,
+		}, {
+			name:             "No retry deadline",
+			method:           http.MethodGet,
+			attemptErrors:    []error{connectionRefusedErr},
+			expectedAttempts: 1,
+			expectErr:        true,
+		}, {
+			name:             "Retries stop at the deadline",
+			method:           http.MethodGet,
+			retryDeadline:    90 * time.Millisecond,
+			attemptErrors:    []error{connectionRefusedErr, connectionRefusedErr, connectionRefusedErr, connectionRefusedErr},
+			expectedAttempts: 3,
+			expectErr:        true,
+		}, {
+			name:             "Bodies larger than the max body size are not retried",
+			method:           http.MethodPost,
+			body:             "test-body-larger-than-max-body-size",
+			retryDeadline:    time.Second,
+			attemptErrors:    []error{connectionRefusedErr},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			attempts := 0
+			transport := newWarmUpRetryTransport(suite.logger,
+				roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					attempts++
+					body, err := io.ReadAll(req.Body)
+					suite.Require().NoError(err)
+					suite.Require().Equal(testCase.body, string(body))
+
+					if attempts <= len(testCase.attemptErrors) {
+						return nil, testCase.attemptErrors[attempts-1]
+					}
+					return &http.Response{StatusCode: http.StatusOK}, nil
+				}),
+				scalertypes.ProxyRetryOptions{
+					WarmUpWindow: scalertypes.Duration{Duration: time.Second},
+					MaxBodySize:  int64(len("test-body")),
+					Backoff:      scalertypes.Duration{Duration: 20 * time.Millisecond},
+					MaxBackoff:   scalertypes.Duration{Duration: 40 * time.Millisecond},
+				})
+
+			testRequest := httptest.NewRequest(testCase.method, "/", strings.NewReader(testCase.body))
+			for headerName, headerValue := range testCase.headers {
+				testRequest.Header.Set(headerName, headerValue)
+			}
+			if testCase.retryDeadline > 0 {
+				testRequest = withRetryDeadline(testRequest, time.Now().Add(testCase.retryDeadline))
+			}
+
+			response, err := transport.RoundTrip(testRequest)
+			if testCase.expectErr {
+				suite.Require().Error(err)
+			} else {
+				suite.Require().NoError(err)
+				suite.Require().Equal(http.StatusOK, response.StatusCode)
+			}
+			suite.Require().Equal(testCase.expectedAttempts, attempts)
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestSelectTargetURL() {
+	resourceNames := []string{"test-targets-name-1", "test-targets-name-2", "test-targets-name-3"}
+	resourceTargetURLMap := map[string]*url.URL{}
+	for _, resourceName := range resourceNames {
+		resourceTargetURLMap[resourceName] = &url.URL{Scheme: "http", Host: resourceName}
+	}
+
+	for _, testCase := range []struct {
+		name                string
+		multiTargetStrategy scalertypes.MultiTargetStrategy
+		resourceNames       []string
+		resourceWeights     []int
+		expectedTargets     map[string]struct{}
+	}{
+		{
+			name:                "Primary strategy ignores weights",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			resourceNames:       resourceNames,
+			resourceWeights:     []int{0, 100, 0},
+			expectedTargets:     map[string]struct{}{"test-targets-name-1": {}},
+		}, {
+			name:                "Canary strategy without weights",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyCanary,
+			resourceNames:       resourceNames,
+			expectedTargets:     map[string]struct{}{"test-targets-name-3": {}},
+		}, {
+			name:                "Canary strategy with weights",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyCanary,
+			resourceNames:       resourceNames,
+			resourceWeights:     []int{0, 100, 0},
+			expectedTargets:     map[string]struct{}{"test-targets-name-2": {}},
+		}, {
+			name:                "Random strategy with weights",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			resourceNames:       resourceNames,
+			resourceWeights:     []int{50, 0, 50},
+			expectedTargets:     map[string]struct{}{"test-targets-name-1": {}, "test-targets-name-3": {}},
+		}, {
+			name:                "Random strategy without weights",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			resourceNames:       resourceNames,
+			expectedTargets: map[string]struct{}{
+				"test-targets-name-1": {},
+				"test-targets-name-2": {},
+				"test-targets-name-3": {},
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+			suite.Require().NoError(err)
+			testHandler.multiTargetStrategy = testCase.multiTargetStrategy
+
+			selectedTargets := map[string]struct{}{}
+			for i := 0; i < 200; i++ {
+				targetURL, err := testHandler.selectTargetURL(httptest.NewRequest(http.MethodGet, "/", nil),
+					testCase.resourceNames,
+					testCase.resourceWeights,
+					resourceTargetURLMap)
+				suite.Require().NoError(err)
+				selectedTargets[targetURL.Host] = struct{}{}
+			}
+			suite.Require().Equal(testCase.expectedTargets, selectedTargets)
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestSelectWeightedResourceName() {
+	testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+	suite.Require().NoError(err)
+
+	resourceNames := []string{"test-targets-name-1", "test-targets-name-2"}
+	selections := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		selections[testHandler.selectWeightedResourceName(resourceNames, []int{90, 10})]++
+	}
+
+	// a 90/10 split, with a generous margin for randomness
+	suite.Require().InDelta(9000, selections["test-targets-name-1"], 500)
+	suite.Require().InDelta(1000, selections["test-targets-name-2"], 500)
+}
+
+func (suite *HandlerTestSuite) TestStickySession() {
+	resourceNames := []string{"test-targets-name-1", "test-targets-name-2", "test-targets-name-3"}
+	for _, testCase := range []struct {
+		name            string
+		stickySession   scalertypes.StickySessionOptions
+		resourceWeights []int
+		createRequest   func(sessionIndex int) *http.Request
+	}{
+		{
+			name: "Cookie key",
+			stickySession: scalertypes.StickySessionOptions{
+				KeySource: scalertypes.StickySessionKeySourceCookie,
+				KeyName:   "session",
+			},
+			createRequest: func(sessionIndex int) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.AddCookie(&http.Cookie{Name: "session", Value: fmt.Sprintf("session-%d", sessionIndex)})
+				return req
+			},
+		}, {
+			name: "Header key with weights",
+			stickySession: scalertypes.StickySessionOptions{
+				KeySource: scalertypes.StickySessionKeySourceHeader,
+				KeyName:   "X-Session-Id",
+			},
+			resourceWeights: []int{20, 30, 50},
+			createRequest: func(sessionIndex int) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Session-Id", fmt.Sprintf("session-%d", sessionIndex))
+				return req
+			},
+		}, {
+			name: "Client IP key",
+			stickySession: scalertypes.StickySessionOptions{
+				KeySource: scalertypes.StickySessionKeySourceClientIP,
+			},
+			createRequest: func(sessionIndex int) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.168.0.1, 10.0.0.%d", sessionIndex))
+				return req
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+			suite.Require().NoError(err)
+			testHandler.multiTargetStrategy = scalertypes.MultiTargetStrategySticky
+			testHandler.stickySession = testCase.stickySession
+
+			// each session consistently hits a single target, while the sessions spread over all the targets
+			selectedTargets := map[string]struct{}{}
+			for sessionIndex := 0; sessionIndex < 100; sessionIndex++ {
+				sessionTarget := testHandler.selectStickyResourceName(testCase.createRequest(sessionIndex),
+					resourceNames,
+					testCase.resourceWeights)
+				for i := 0; i < 5; i++ {
+					suite.Require().Equal(sessionTarget, testHandler.selectStickyResourceName(
+						testCase.createRequest(sessionIndex),
+						resourceNames,
+						testCase.resourceWeights))
+				}
+				selectedTargets[sessionTarget] = struct{}{}
+			}
+			suite.Require().Len(selectedTargets, len(resourceNames))
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestStickySessionClientIP() {
+	testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+	suite.Require().NoError(err)
+	testHandler.stickySession = scalertypes.StickySessionOptions{
+		KeySource: scalertypes.StickySessionKeySourceClientIP,
+	}
+
+	// only the address added by the ingress is trusted, as the client may spoof the addresses before it
+	testRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+	testRequest.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	testRequest.Header.Add("X-Forwarded-For", "3.3.3.3, 10.0.0.1")
+	suite.Require().Equal("10.0.0.1", testHandler.getStickySessionKey(testRequest))
+
+	// without a forwarded address, the remote address is the client's
+	testRequest = httptest.NewRequest(http.MethodGet, "/", nil)
+	testRequest.RemoteAddr = "10.0.0.2:12345"
+	suite.Require().Equal("10.0.0.2", testHandler.getStickySessionKey(testRequest))
+}
+
+func (suite *HandlerTestSuite) TestStickySessionAffinityCookie() {
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, &kube.IngressValue{
+		Host:    "www.example.com",
+		Path:    "test/path",
+		Targets: []string{"test-targets-name-1", "test-targets-name-2"},
+	})
+	suite.Require().NoError(err)
+	testHandler.resourceStarter = &ResourceStarter{
+		logger:                   suite.logger,
+		scaler:                   suite.scaler,
+		resourceReadinessTimeout: 3 * time.Second,
+	}
+	testHandler.multiTargetStrategy = scalertypes.MultiTargetStrategySticky
+	testHandler.stickySession = scalertypes.StickySessionOptions{
+		KeySource:          scalertypes.StickySessionKeySourceHeader,
+		KeyName:            "X-Session-Id",
+		AffinityCookieName: "dlx-affinity",
+	}
+
+	// the affinity cookie wins over the session key
+	for _, affinityTarget := range []string{"test-targets-name-1", "test-targets-name-2"} {
+		testRequest := suite.createTestHTTPRequest("affinity", nil, "www.example.com", "test/path")
+		testRequest.Header.Set("X-Session-Id", "session")
+		testRequest.AddCookie(&http.Cookie{Name: "dlx-affinity", Value: affinityTarget})
+		suite.Require().Equal(affinityTarget, testHandler.selectStickyResourceName(testRequest,
+			[]string{"test-targets-name-1", "test-targets-name-2"},
+			nil))
+	}
+
+	// the served target is pinned in the affinity cookie
+	testRequest := suite.createTestHTTPRequest("affinity", nil, "www.example.com", "test/path")
+	testRequest.Header.Set("X-Session-Id", "session")
+	testResponse := httptest.NewRecorder()
+	testHandler.handleRequest(testResponse, testRequest)
+	suite.Require().Equal(http.StatusOK, testResponse.Code)
+
+	cookies := testResponse.Result().Cookies()
+	suite.Require().Len(cookies, 1)
+	suite.Require().Equal("dlx-affinity", cookies[0].Name)
+	suite.Require().Contains([]string{"test-targets-name-1", "test-targets-name-2"}, cookies[0].Value)
+}
+
+func (suite *HandlerTestSuite) TestCanaryOverride() {
+	resourceNames := []string{"test-targets-name-1", "test-targets-name-2"}
+	resourceTargetURLMap := map[string]*url.URL{}
+	for _, resourceName := range resourceNames {
+		resourceTargetURLMap[resourceName] = &url.URL{Scheme: "http", Host: resourceName}
+	}
+
+	for _, testCase := range []struct {
+		name                string
+		multiTargetStrategy scalertypes.MultiTargetStrategy
+		canaryOverride      scalertypes.CanaryOverrideOptions
+		headers             map[string]string
+		cookies             map[string]string
+		expectedTarget      string
+	}{
+		{
+			name:                "Header always",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary"},
+			headers:             map[string]string{"X-Canary": "always"},
+			expectedTarget:      "test-targets-name-2",
+		}, {
+			name:                "Header never",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyCanary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary"},
+			headers:             map[string]string{"X-Canary": "never"},
+			expectedTarget:      "test-targets-name-1",
+		}, {
+			name:                "Header with other value is ignored",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary"},
+			headers:             map[string]string{"X-Canary": "sometimes"},
+			expectedTarget:      "test-targets-name-1",
+		}, {
+			name:                "Header value match",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary", HeaderValue: "qa"},
+			headers:             map[string]string{"X-Canary": "qa"},
+			expectedTarget:      "test-targets-name-2",
+		}, {
+			name:                "Header value replaces always",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary", HeaderValue: "qa"},
+			headers:             map[string]string{"X-Canary": "always"},
+			expectedTarget:      "test-targets-name-1",
+		}, {
+			name:                "Header pattern match",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary", HeaderPattern: "^qa-[0-9]+$"},
+			headers:             map[string]string{"X-Canary": "qa-17"},
+			expectedTarget:      "test-targets-name-2",
+		}, {
+			name:                "Cookie always",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Cookie: "canary"},
+			cookies:             map[string]string{"canary": "always"},
+			expectedTarget:      "test-targets-name-2",
+		}, {
+			name:                "Header takes precedence over cookie",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary", Cookie: "canary"},
+			headers:             map[string]string{"X-Canary": "never"},
+			cookies:             map[string]string{"canary": "always"},
+			expectedTarget:      "test-targets-name-1",
+		}, {
+			name:                "Unmatched header falls back to cookie",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyPrimary,
+			canaryOverride:      scalertypes.CanaryOverrideOptions{Header: "X-Canary", HeaderValue: "qa", Cookie: "canary"},
+			headers:             map[string]string{"X-Canary": "other"},
+			cookies:             map[string]string{"canary": "always"},
+			expectedTarget:      "test-targets-name-2",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+			suite.Require().NoError(err)
+			testHandler.multiTargetStrategy = testCase.multiTargetStrategy
+			testHandler.canaryOverride = testCase.canaryOverride
+			if testCase.canaryOverride.HeaderPattern != "" {
+				testHandler.canaryHeaderPattern = regexp.MustCompile(testCase.canaryOverride.HeaderPattern)
+			}
+
+			testRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+			for headerName, headerValue := range testCase.headers {
+				testRequest.Header.Set(headerName, headerValue)
+			}
+			for cookieName, cookieValue := range testCase.cookies {
+				testRequest.AddCookie(&http.Cookie{Name: cookieName, Value: cookieValue})
+			}
+
+			targetURL, err := testHandler.selectTargetURL(testRequest, resourceNames, nil, resourceTargetURLMap)
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedTarget, targetURL.Host)
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestIsUpgradeRequest() {
+	for _, testCase := range []struct {
+		name     string
+		headers  map[string][]string
+		expected bool
+	}{
+		{
+			name:     "WebSocket upgrade",
+			headers:  map[string][]string{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}},
+			expected: true,
+		}, {
+			name:     "Upgrade among connection tokens",
+			headers:  map[string][]string{"Connection": {"keep-alive, upgrade"}, "Upgrade": {"websocket"}},
+			expected: true,
+		}, {
+			name:     "Upgrade without connection header",
+			headers:  map[string][]string{"Upgrade": {"websocket"}},
+			expected: false,
+		}, {
+			name:     "Connection upgrade without upgrade header",
+			headers:  map[string][]string{"Connection": {"Upgrade"}},
+			expected: false,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+			testRequest.Header = testCase.headers
+			suite.Require().Equal(testCase.expected, isUpgradeRequest(testRequest))
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestUpgradeRequest() {
+
+	// a target that switches to an echo protocol, and echoes back the websocket key it received
+	upgradeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUpgradeRequest(r) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, bufferedReadWriter, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+
+		fmt.Fprintf(bufferedReadWriter, // nolint: errcheck
+			"HTTP/1.1 101 Switching Protocols\r\nUpgrade: %s\r\nConnection: Upgrade\r\nX-Echo-Key: %s\r\n\r\n",
+			r.Header.Get("Upgrade"),
+			r.Header.Get("Sec-WebSocket-Key"))
+		bufferedReadWriter.Flush()               // nolint: errcheck
+		io.Copy(conn, bufferedReadWriter.Reader) // nolint: errcheck
+	}))
+	defer upgradeServer.Close()
+
+	upgradeServerURL, err := url.Parse(upgradeServer.URL)
+	suite.Require().NoError(err)
+	upgradeServerPort, err := strconv.Atoi(upgradeServerURL.Port())
+	suite.Require().NoError(err)
+
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return(upgradeServerURL.Hostname(), nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	testHandler, err := suite.createTestHandlerAndInitTestCache(upgradeServerPort, &kube.IngressValue{
+		Host:    "www.example.com",
+		Path:    "/test/path",
+		Targets: []string{"test-targets-name-1"},
+	})
+	suite.Require().NoError(err)
+
+	// short server timeouts, which must not apply to the upgraded connection
+	dlxServer := httptest.NewUnstartedServer(http.HandlerFunc(testHandler.handleRequest))
+	dlxServer.Config.ReadTimeout = 100 * time.Millisecond
+	dlxServer.Config.WriteTimeout = 100 * time.Millisecond
+	dlxServer.Start()
+	defer dlxServer.Close()
+
+	conn, err := net.Dial("tcp", dlxServer.Listener.Addr().String())
+	suite.Require().NoError(err)
+	defer conn.Close() // nolint: errcheck
+
+	_, err = fmt.Fprint(conn, "GET /test/path HTTP/1.1\r\n"+
+		"Host: www.example.com\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Upgrade: echo\r\n"+
+		"Sec-WebSocket-Key: test-key\r\n\r\n")
+	suite.Require().NoError(err)
+
+	connReader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(connReader, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusSwitchingProtocols, response.StatusCode)
+	suite.Require().Equal("echo", response.Header.Get("Upgrade"))
+	suite.Require().Equal("test-key", response.Header.Get("X-Echo-Key"))
+	suite.Require().Equal(1, testHandler.upgradeTracker.count())
+
+	// evicting the proxy from the cache and outliving the server timeouts keeps the connection open
+	for _, key := range testHandler.targetURLCache.Keys() {
+		testHandler.targetURLCache.Remove(key)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	_, err = fmt.Fprint(conn, "ping\n")
+	suite.Require().NoError(err)
+	echoed, err := connReader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Require().Equal("ping\n", echoed)
+
+	// waiting for the upgraded connections fails while the connection is open
+	waitCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	suite.Require().Error(testHandler.upgradeTracker.wait(waitCtx))
+
+	suite.Require().NoError(conn.Close())
+	waitCtx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	suite.Require().NoError(testHandler.upgradeTracker.wait(waitCtx))
+	suite.Require().Equal(0, testHandler.upgradeTracker.count())
+}
+
+func (suite *HandlerTestSuite) TestGRPCProxy() {
+
+	// a cleartext HTTP/2 target that streams back every message it receives, and ends the call with trailers
+	grpcServer := httptest.NewUnstartedServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Te") != "trailers" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+
+		// send the headers before the first message, so the call is established before messages are streamed
+		responseController := http.NewResponseController(w)
+		responseController.Flush() // nolint: errcheck
+		buffer := make([]byte, 1024)
+		for {
+			readBytes, err := r.Body.Read(buffer)
+			if readBytes > 0 {
+				w.Write(buffer[:readBytes]) // nolint: errcheck
+				responseController.Flush()  // nolint: errcheck
+			}
+			if err != nil {
+				break
+			}
+		}
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}), &http2.Server{}))
+	grpcServer.Start()
+	defer grpcServer.Close()
+
+	grpcServerURL, err := url.Parse(grpcServer.URL)
+	suite.Require().NoError(err)
+	grpcServerPort, err := strconv.Atoi(grpcServerURL.Port())
+	suite.Require().NoError(err)
+
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return(grpcServerURL.Hostname(), nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	testHandler, err := suite.createTestHandlerAndInitTestCache(grpcServerPort, &kube.IngressValue{
+		Host:    "www.example.com",
+		Path:    "/test/path",
+		Targets: []string{"test-targets-name-1"},
+	})
+	suite.Require().NoError(err)
+	testHandler.transport = newProtocolTransport(testHandler.transport, scalertypes.ProxyTransportOptions{})
+
+	dlxServer := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(testHandler.handleRequest), &http2.Server{}))
+	defer dlxServer.Close()
+
+	h2cClient := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	requestBodyReader, requestBodyWriter := io.Pipe()
+	testRequest, err := http.NewRequest(http.MethodPost, dlxServer.URL+"/test/path", requestBodyReader)
+	suite.Require().NoError(err)
+	testRequest.Host = "www.example.com"
+	testRequest.Header.Set("Content-Type", "application/grpc")
+	testRequest.Header.Set("Te", "trailers")
+
+	testResponse, err := h2cClient.Do(testRequest)
+	suite.Require().NoError(err)
+	defer testResponse.Body.Close() // nolint: errcheck
+	suite.Require().Equal(http.StatusOK, testResponse.StatusCode)
+
+	// messages are streamed both ways while the call is open
+	responseBuffer := make([]byte, 1024)
+	for _, message := range []string{"first", "second"} {
+		_, err = requestBodyWriter.Write([]byte(message))
+		suite.Require().NoError(err)
+		readBytes, err := testResponse.Body.Read(responseBuffer)
+		suite.Require().NoError(err)
+		suite.Require().Equal(message, string(responseBuffer[:readBytes]))
+	}
+
+	suite.Require().NoError(requestBodyWriter.Close())
+	_, err = io.ReadAll(testResponse.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal("0", testResponse.Trailer.Get("Grpc-Status"))
+}
+
+func (suite *HandlerTestSuite) TestGRPCWakeErrors() {
+	for _, testCase := range []struct {
+		name               string
+		setScaleErr        error
+		setScaleDelay      time.Duration
+		expectedGRPCStatus string
+	}{
+		{
+			name:               "Wake failure",
+			setScaleErr:        errors.New("failed to scale"),
+			expectedGRPCStatus: strconv.Itoa(grpcStatusUnavailable),
+		}, {
+			name:               "Wake timeout",
+			setScaleDelay:      500 * time.Millisecond,
+			expectedGRPCStatus: strconv.Itoa(grpcStatusDeadlineExceeded),
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.scaler.ExpectedCalls = nil
+			suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+			suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				After(testCase.setScaleDelay).
+				Return(testCase.setScaleErr)
+			suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, &kube.IngressValue{
+				Host:    "www.example.com",
+				Path:    "test/path",
+				Targets: []string{"test-targets-name-1"},
+			})
+			suite.Require().NoError(err)
+			testHandler.resourceStarter = &ResourceStarter{
+				logger:                   suite.logger,
+				scaler:                   suite.scaler,
+				resourceReadinessTimeout: 100 * time.Millisecond,
+			}
+
+			testRequest := suite.createTestHTTPRequest(testCase.name, nil, "www.example.com", "test/path")
+			testRequest.ProtoMajor = 2
+			testRequest.Header.Set("Content-Type", "application/grpc")
+			testResponse := httptest.NewRecorder()
+			testHandler.handleRequest(testResponse, testRequest)
+
+			// gRPC calls report their status in a trailers-only response
+			suite.Require().Equal(http.StatusOK, testResponse.Code)
+			suite.Require().Equal("application/grpc", testResponse.Header().Get("Content-Type"))
+			suite.Require().Equal(testCase.expectedGRPCStatus, testResponse.Header().Get("Grpc-Status"))
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestWakeSelectedTargetOnly() {
+	for _, testCase := range []struct {
+		name                   string
+		wakeSelectedTargetOnly bool
+		lazyWakeOtherTargets   bool
+		expectedStartedTargets []string
+	}{
+		{
+			name:                   "Wake all targets",
+			expectedStartedTargets: []string{"test-targets-name-1", "test-targets-name-2"},
+		}, {
+			name:                   "Wake selected target only",
+			wakeSelectedTargetOnly: true,
+			expectedStartedTargets: []string{"test-targets-name-1"},
+		}, {
+			name:                   "Wake selected target and other targets lazily",
+			wakeSelectedTargetOnly: true,
+			lazyWakeOtherTargets:   true,
+			expectedStartedTargets: []string{"test-targets-name-1", "test-targets-name-2"},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			startedTargetsLock := sync.Mutex{}
+			startedTargets := map[string]struct{}{}
+			suite.scaler.ExpectedCalls = nil
+			suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+			suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+			suite.scaler.
+				On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					startedTargetsLock.Lock()
+					defer startedTargetsLock.Unlock()
+					startedTargets[args.Get(1).([]scalertypes.Resource)[0].Name] = struct{}{}
+				}).
+				Return(nil)
+
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, &kube.IngressValue{
+				Host:    "www.example.com",
+				Path:    "test/path",
+				Targets: []string{"test-targets-name-1", "test-targets-name-2"},
+			})
+			suite.Require().NoError(err)
+			testHandler.resourceStarter = &ResourceStarter{
+				logger:                   suite.logger,
+				scaler:                   suite.scaler,
+				resourceReadinessTimeout: 3 * time.Second,
+			}
+			testHandler.wakeSelectedTargetOnly = testCase.wakeSelectedTargetOnly
+			testHandler.lazyWakeOtherTargets = testCase.lazyWakeOtherTargets
+
+			testRequest := suite.createTestHTTPRequest(testCase.name, nil, "www.example.com", "test/path")
+			testResponse := httptest.NewRecorder()
+			testHandler.handleRequest(testResponse, testRequest)
+			suite.Require().Equal(http.StatusOK, testResponse.Code)
+
+			// other targets are woken lazily, so wait for them
+			expectedStartedTargets := map[string]struct{}{}
+			for _, targetName := range testCase.expectedStartedTargets {
+				expectedStartedTargets[targetName] = struct{}{}
+			}
+			suite.Require().Eventually(func() bool {
+				startedTargetsLock.Lock()
+				defer startedTargetsLock.Unlock()
+				return len(startedTargets) == len(expectedStartedTargets)
+			}, time.Second, 10*time.Millisecond)
+
+			startedTargetsLock.Lock()
+			defer startedTargetsLock.Unlock()
+			suite.Require().Equal(expectedStartedTargets, startedTargets)
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestFailoverStrategy() {
+	for _, testCase := range []struct {
+		name                   string
+		wakeSelectedTargetOnly bool
+		failingTargets         map[string]struct{}
+		expectedStatus         int
+		expectedServedTarget   string
+	}{
+		{
+			name:                 "Primary starts",
+			expectedStatus:       http.StatusOK,
+			expectedServedTarget: "test-targets-name-1",
+		}, {
+			name:                 "Primary fails, failover to secondary",
+			failingTargets:       map[string]struct{}{"test-targets-name-1": {}},
+			expectedStatus:       http.StatusOK,
+			expectedServedTarget: "test-targets-name-2",
+		}, {
+			name:                 "Secondary fails, primary serves",
+			failingTargets:       map[string]struct{}{"test-targets-name-2": {}},
+			expectedStatus:       http.StatusOK,
+			expectedServedTarget: "test-targets-name-1",
+		}, {
+			name:                   "Primary fails, failover to secondary when waking selected target only",
+			wakeSelectedTargetOnly: true,
+			failingTargets:         map[string]struct{}{"test-targets-name-1": {}},
+			expectedStatus:         http.StatusOK,
+			expectedServedTarget:   "test-targets-name-2",
+		}, {
+			name: "All targets fail",
+			failingTargets: map[string]struct{}{
+				"test-targets-name-1": {},
+				"test-targets-name-2": {},
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.scaler.ExpectedCalls = nil
+			suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+			suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+			for _, targetName := range []string{"test-targets-name-1", "test-targets-name-2"} {
+				var setScaleErr error
+				if _, failing := testCase.failingTargets[targetName]; failing {
+					setScaleErr = errors.New("failed to scale")
+				}
+				suite.scaler.
+					On("SetScaleCtx", mock.Anything, mock.MatchedBy(func(resources []scalertypes.Resource) bool {
+						return resources[0].Name == targetName
+					}), mock.Anything).
+					Return(setScaleErr)
+			}
+
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, &kube.IngressValue{
+				Host:    "www.example.com",
+				Path:    "test/path",
+				Targets: []string{"test-targets-name-1", "test-targets-name-2"},
+			})
+			suite.Require().NoError(err)
+			testHandler.resourceStarter = &ResourceStarter{
+				logger:                   suite.logger,
+				scaler:                   suite.scaler,
+				resourceReadinessTimeout: 3 * time.Second,
+			}
+			testHandler.multiTargetStrategy = scalertypes.MultiTargetStrategyFailover
+			testHandler.wakeSelectedTargetOnly = testCase.wakeSelectedTargetOnly
+
+			testRequest := suite.createTestHTTPRequest(testCase.name, nil, "www.example.com", "test/path")
+			testResponse := httptest.NewRecorder()
+			testHandler.handleRequest(testResponse, testRequest)
+
+			suite.Require().Equal(testCase.expectedStatus, testResponse.Code)
+			suite.Require().Equal(testCase.expectedServedTarget, testResponse.Header().Get(servedTargetHeader))
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestAbortRequests() {
+
+	// a target that answers only once the request is canceled
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slowServer.Close()
+	slowServerURL, err := url.Parse(slowServer.URL)
+	suite.Require().NoError(err)
+	slowServerPort, err := strconv.Atoi(slowServerURL.Port())
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		name          string
+		targetPort    int
+		setScaleDelay time.Duration
+	}{
+		{
+			name:          "Waiting for the resource to start",
+			targetPort:    suite.backendPort,
+			setScaleDelay: 2 * time.Second,
+		}, {
+			name:       "Proxied to the resource",
+			targetPort: slowServerPort,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.scaler.ExpectedCalls = nil
+			suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+			suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				After(testCase.setScaleDelay).
+				Return(nil)
+			suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+			testHandler, err := suite.createTestHandlerAndInitTestCache(testCase.targetPort, &kube.IngressValue{
+				Host:    "www.example.com",
+				Path:    "test/path",
+				Targets: []string{"test-targets-name-1"},
+			})
+			suite.Require().NoError(err)
+
+			testRequest := suite.createTestHTTPRequest(testCase.name, nil, "www.example.com", "test/path")
+			testResponse := httptest.NewRecorder()
+			handled := make(chan struct{})
+			go func() {
+				defer close(handled)
+				testHandler.handleRequest(testResponse, testRequest)
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			testHandler.abortRequests()
+
+			select {
+			case <-handled:
+			case <-time.After(time.Second):
+				suite.Fail("Aborted request was not released")
+			}
+			suite.Require().Equal(http.StatusServiceUnavailable, testResponse.Code)
+			suite.Require().Equal("1", testResponse.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestResourceStartErrorBodies() {
+	hostTemplateFile := filepath.Join(suite.T().TempDir(), "custom.html")
+	suite.Require().NoError(os.WriteFile(hostTemplateFile,
+		[]byte(`custom page of {{.Resource}}, retrying in {{.RetryAfter}}`),
+		0600))
+	testErrorPageWriter, err := newErrorPageWriter(scalertypes.ErrorPageOptions{
+		HostTemplateFiles: map[string]string{"custom.example.com": hostTemplateFile},
+		RefreshInterval:   scalertypes.Duration{Duration: 3 * time.Second},
+	})
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		name                string
+		host                string
+		accept              string
+		statusResult        ResourceStatusResult
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:   "JSON body",
+			accept: "application/json",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusGatewayTimeout,
+				Error:        errors.New("timed out"),
+			},
+			expectedContentType: "application/json",
+			expectedBody:        `{"resource":"test-resource","reason":"Timed out waiting for the resource to wake up"}`,
+		}, {
+			name: "JSON body with retry after",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusTooManyRequests,
+				Error:        ErrTooManyWaitingRequests,
+				RetryAfter:   1500 * time.Millisecond,
+			},
+			expectedContentType: "application/json",
+			expectedBody: `{"resource":"test-resource",` +
+				`"reason":"Too many requests are waiting for the resource to wake up","retryAfter":2}`,
+		}, {
+			name:   "Default page",
+			accept: "text/html,application/xhtml+xml",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusGatewayTimeout,
+				Error:        errors.New("timed out"),
+			},
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        `<meta http-equiv="refresh" content="3">`,
+		}, {
+			name:   "Waking page",
+			accept: "text/html",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusServiceUnavailable,
+				Error:        ErrResourceWaking,
+			},
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody: `<meta http-equiv="refresh" content="3">
+<title>Waking up test-resource</title>`,
+		}, {
+			name:   "Failure page is not refreshed",
+			accept: "text/html",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusInternalServerError,
+				Error:        errors.New("failed"),
+			},
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody: `<meta charset="utf-8">
+<title>Failed to wake up test-resource</title>`,
+		}, {
+			name:   "Host page",
+			host:   "custom.example.com:8080",
+			accept: "text/html",
+			statusResult: ResourceStatusResult{
+				ResourceName: "test-resource",
+				Status:       http.StatusServiceUnavailable,
+				Error:        ErrWarmResourcesBudgetExceeded,
+				RetryAfter:   time.Second,
+			},
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        "custom page of test-resource, retrying in 1",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, nil)
+			suite.Require().NoError(err)
+			testHandler.errorPageWriter = testErrorPageWriter
+
+			testRequest := suite.createTestHTTPRequest(testCase.name, nil, testCase.host, "")
+			testRequest.Header.Set("Accept", testCase.accept)
+			testResponse := httptest.NewRecorder()
+			testHandler.writeResourceStartError(testResponse, testRequest, &testCase.statusResult)
+
+			suite.Require().Equal(testCase.statusResult.Status, testResponse.Code)
+			suite.Require().Equal(testCase.expectedContentType, testResponse.Header().Get("Content-Type"))
+			suite.Require().Contains(testResponse.Body.String(), testCase.expectedBody)
+		})
+	}
+}
+
+func (suite *HandlerTestSuite) TestWakePage() {
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		After(2 * time.Second).
+		Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, &kube.IngressValue{
+		Host:    "www.example.com",
+		Path:    "test/path",
+		Targets: []string{"test-targets-name-1"},
+	})
+	suite.Require().NoError(err)
+	testHandler.wakePageGracePeriod = 500 * time.Millisecond
+	createBrowserRequest := func(method string) *http.Request {
+		testRequest := suite.createTestHTTPRequest("browser", nil, "www.example.com", "test/path")
+		testRequest.Method = method
+		testRequest.Header.Set("Accept", "text/html,application/xhtml+xml")
+		return testRequest
+	}
+
+	// a form submitted by a browser would not be resent by the refresh of the page, so it waits for the wake-up
+	postResponseChan := make(chan int, 1)
+	go func() {
+		testResponse := httptest.NewRecorder()
+		testHandler.handleRequest(testResponse, createBrowserRequest(http.MethodPost))
+		postResponseChan <- testResponse.Code
+	}()
+
+	// a page load of a browser is shown the waking page without waiting for the resource to wake up
+	startTime := time.Now()
+	testResponse := httptest.NewRecorder()
+	testHandler.handleRequest(testResponse, createBrowserRequest(http.MethodGet))
+	suite.Require().Less(time.Since(startTime), 2*time.Second)
+	suite.Require().Equal(http.StatusServiceUnavailable, testResponse.Code)
+	suite.Require().Contains(testResponse.Body.String(), "test-targets-name-1 is waking up")
+
+	// the page load is no longer waiting, while the form still is
+	suite.Require().Eventually(func() bool {
+		totalWaitingRequests, _ := suite.starter.GetWaitingRequests()
+		return totalWaitingRequests == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the resource keeps waking up in the background, and is served once it is ready
+	suite.Require().Eventually(func() bool {
+		testResponse := httptest.NewRecorder()
+		testHandler.handleRequest(testResponse, createBrowserRequest(http.MethodGet))
+		return testResponse.Code == http.StatusOK
+	}, 5*time.Second, 200*time.Millisecond)
+	suite.Require().Equal(http.StatusOK, <-postResponseChan)
+	suite.scaler.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+}
+
+func (suite *HandlerTestSuite) TestShouldShowWakePage() {
+	for _, testCase := range []struct {
+		name                string
+		wakePageGracePeriod time.Duration
+		method              string
+		headers             map[string]string
+		expected            bool
+	}{
+		{
+			name:                "Page load",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodGet,
+			headers:             map[string]string{"Accept": "text/html"},
+			expected:            true,
+		}, {
+			name:                "Head request",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodHead,
+			headers:             map[string]string{"Accept": "text/html"},
+			expected:            true,
+		}, {
+			name:    "Disabled",
+			method:  http.MethodGet,
+			headers: map[string]string{"Accept": "text/html"},
+		}, {
+			name:                "Form submission",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodPost,
+			headers:             map[string]string{"Accept": "text/html"},
+		}, {
+			name:                "Put request",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodPut,
+			headers:             map[string]string{"Accept": "text/html"},
+		}, {
+			name:                "API client",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodGet,
+			headers:             map[string]string{"Accept": "application/json"},
+		}, {
+			name:                "gRPC request",
+			wakePageGracePeriod: time.Second,
+			method:              http.MethodGet,
+			headers:             map[string]string{"Accept": "text/html", "Content-Type": "application/grpc"},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testHandler := Handler{wakePageGracePeriod: testCase.wakePageGracePeriod}
+			testRequest := httptest.NewRequest(testCase.method, "/", nil)
+			testRequest.ProtoMajor = 2
+			for headerName, headerValue := range testCase.headers {
+				testRequest.Header.Set(headerName, headerValue)
+			}
+			suite.Require().Equal(testCase.expected, testHandler.shouldShowWakePage(testRequest))
+		})
+	}
+}
+
 func (suite *HandlerTestSuite) TestGetPathAndResourceNames() {
 	for _, testCase := range []struct {
 		name                  string
@@ -247,7 +1429,7 @@ func (suite *HandlerTestSuite) TestGetPathAndResourceNames() {
 			testHandler, err := suite.createTestHandlerAndInitTestCache(suite.backendPort, testCase.initialCachedData)
 			suite.Require().NoError(err)
 			testRequest := suite.createTestHTTPRequest(testCase.name, testCase.reqHeaders, testCase.reqHost, testCase.reqPath)
-			resultResourceNames, err := testHandler.getResourceNames(testRequest)
+			resultResourceNames, _, err := testHandler.getResourceNames(testRequest)
 
 			// validate the result
 			if testCase.expectErr {
@@ -263,6 +1445,12 @@ func (suite *HandlerTestSuite) TestGetPathAndResourceNames() {
 
 // --- HandlerTestSuite suite methods ---
 
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 func (suite *HandlerTestSuite) createTestHandlerAndInitTestCache(targetPort int, initialCachedData *kube.IngressValue) (Handler, error) {
 	testIngressCache := ingresscache.NewIngressCache(suite.logger)
 	if initialCachedData != nil {
@@ -280,6 +1468,15 @@ func (suite *HandlerTestSuite) createTestHandlerAndInitTestCache(targetPort int,
 		targetPort,
 		scalertypes.MultiTargetStrategyPrimary,
 		testIngressCache,
+		scalertypes.ProxyTransportOptions{},
+		scalertypes.ProxyRetryOptions{},
+		false,
+		false,
+		scalertypes.StickySessionOptions{},
+		scalertypes.CanaryOverrideOptions{},
+		false,
+		scalertypes.ErrorPageOptions{},
+		scalertypes.AsyncWakeOptions{},
 	)
 }
 
@@ -327,9 +1524,11 @@ func (suite *HandlerTestSuite) setScalerMocksBasedOnTestCase(
 	case "Request headers flow",
 		"Request headers negative flow - duplicate request path should fail":
 		suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
 	default:
 		suite.scaler.On("ResolveServiceName", mock.Anything).Return(suite.backendHost, resolveServiceNameErr)
 		suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
 	}
 }
 
@@ -0,0 +1,251 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/v3io/scaler/pkg/ingresscache"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// ResourceResolver returns the names of the resources a request targets, along with their routing weights (nil when
+// the targets are not weighted)
+type ResourceResolver func(req *http.Request) ([]string, []int, error)
+
+// Target is the resource selected for a request, passed to the next handler of a middleware through the request context
+type Target struct {
+	ResourceName string
+	URL          *url.URL
+}
+
+type targetContextKey struct{}
+
+// TargetFromContext returns the target selected for the request by the middleware in front of the handler
+func TargetFromContext(ctx context.Context) (Target, bool) {
+	target, ok := ctx.Value(targetContextKey{}).(Target)
+	return target, ok
+}
+
+// Middleware wakes the resources a request targets before serving it - either by proxying the request to the selected
+// target, or by passing it on to a next handler. it lets servers such as API gateways scale resources from zero
+// without running the DLX as a separate hop
+type Middleware struct {
+	handler         *Handler
+	resourceStarter *ResourceStarter
+}
+
+type middlewareConfig struct {
+	options          scalertypes.DLXOptions
+	resourceScaler   scalertypes.ResourceScaler
+	resourceResolver ResourceResolver
+	nextHandler      http.Handler
+	ingressCache     ingresscache.IngressHostCacheReader
+	readinessCache   *ReadinessCache
+}
+
+// MiddlewareOption configures a middleware
+type MiddlewareOption func(*middlewareConfig)
+
+// WithDLXOptions configures the middleware as a DLX. options given after it override the corresponding DLX options
+func WithDLXOptions(options scalertypes.DLXOptions) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options = options
+	}
+}
+
+// WithResourceScaler sets the scaler waking the resources, which is required
+func WithResourceScaler(resourceScaler scalertypes.ResourceScaler) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.resourceScaler = resourceScaler
+	}
+}
+
+// WithResourceResolver resolves the resources of requests, instead of reading their names from the target name header
+func WithResourceResolver(resourceResolver ResourceResolver) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.resourceResolver = resourceResolver
+	}
+}
+
+// WithTargetNameHeader reads the comma delimited names of the resources of requests from the given header
+func WithTargetNameHeader(targetNameHeader string) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.TargetNameHeader = targetNameHeader
+	}
+}
+
+// WithNamespace sets the namespace of the resources
+func WithNamespace(namespace string) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.Namespace = namespace
+	}
+}
+
+// WithTargetPort sets the port of the resource services that requests are proxied to
+func WithTargetPort(targetPort int) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.TargetPort = targetPort
+	}
+}
+
+// WithMultiTargetStrategy sets how the target of a request to several resources is selected
+func WithMultiTargetStrategy(multiTargetStrategy scalertypes.MultiTargetStrategy) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.MultiTargetStrategy = multiTargetStrategy
+	}
+}
+
+// WithResourceReadinessTimeout sets how long a request waits for its resources to be ready
+func WithResourceReadinessTimeout(resourceReadinessTimeout time.Duration) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.ResourceReadinessTimeout = scalertypes.Duration{Duration: resourceReadinessTimeout}
+	}
+}
+
+// WithProxyTransport configures the transport proxying requests to their targets
+func WithProxyTransport(proxyTransportOptions scalertypes.ProxyTransportOptions) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.options.ProxyTransport = proxyTransportOptions
+	}
+}
+
+// WithNextHandler passes the requests on to the next handler once their resources are ready, instead of proxying them.
+// the selected target is available to it through TargetFromContext
+func WithNextHandler(nextHandler http.Handler) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.nextHandler = nextHandler
+	}
+}
+
+func withIngressCache(ingressCache ingresscache.IngressHostCacheReader) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.ingressCache = ingressCache
+	}
+}
+
+// WithReadinessCache answers the requests to the resources known to be ready without starting them again. async wake
+// routes require it, as they tell the requests that wake their resources from the ones that don't by it
+func WithReadinessCache(readinessCache *ReadinessCache) MiddlewareOption {
+	return func(config *middlewareConfig) {
+		config.readinessCache = readinessCache
+	}
+}
+
+func NewMiddleware(parentLogger logger.Logger, middlewareOptions ...MiddlewareOption) (*Middleware, error) {
+	config := &middlewareConfig{}
+	for _, middlewareOption := range middlewareOptions {
+		middlewareOption(config)
+	}
+
+	if config.resourceScaler == nil {
+		return nil, errors.New("Resource scaler is required")
+	}
+	if config.resourceResolver == nil && config.ingressCache == nil && config.options.TargetNameHeader == "" {
+		return nil, errors.New("Either a resource resolver or a target name header is required")
+	}
+
+	options := config.options
+	resourceStarter, err := NewResourceStarter(parentLogger,
+		config.resourceScaler,
+		options.Namespace,
+		options.ResourceReadinessTimeout.Duration,
+		options.WaitForDependencies,
+		options.WarmResourcesBudget,
+		options.WakeRequestsPerReplica,
+		options.MaxWakeReplicas,
+		options.MaxWaitingRequestsPerResource,
+		options.MaxWaitingRequests,
+		config.readinessCache,
+		options.ReadinessProbe,
+		options.TargetPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create function starter")
+	}
+
+	handler, err := NewHandler(parentLogger,
+		resourceStarter,
+		config.resourceScaler,
+		options.TargetNameHeader,
+		options.TargetPathHeader,
+		options.TargetPort,
+		options.MultiTargetStrategy,
+		config.ingressCache,
+		options.ProxyTransport,
+		options.ProxyRetry,
+		options.WakeSelectedTargetOnly,
+		options.LazyWakeOtherTargets,
+		options.StickySession,
+		options.CanaryOverride,
+		options.EnableHTTP2,
+		options.ErrorPage,
+		options.AsyncWake)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create handler")
+	}
+	handler.resourceResolver = config.resourceResolver
+	handler.nextHandler = config.nextHandler
+	handler.HandleFunc = handler.handleRequest
+
+	// the requests queued to a resource are replayed whenever it wakes up, including by requests to other routes
+	if handler.asyncWaker.requestQueue != nil {
+		resourceStarter.resourceStartedCallback = handler.replayQueuedRequests
+	}
+
+	return &Middleware{
+		handler:         &handler,
+		resourceStarter: resourceStarter,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler
+func (m *Middleware) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	m.handler.ServeHTTP(res, req)
+}
+
+// Start replays the requests that async routes queued before a restart, once their resources wake up. servers
+// embedding the middleware call it before serving requests
+func (m *Middleware) Start() error {
+	if err := m.handler.replayPendingRequests(); err != nil {
+		return errors.Wrap(err, "Failed to replay pending requests")
+	}
+	return nil
+}
+
+// Stop aborts the requests still in flight, including the replays of queued requests, and ends the wake-ups in
+// progress. servers embedding the middleware call it on shutdown, once their in-flight requests were drained or not
+// drained in time
+func (m *Middleware) Stop() {
+	m.handler.abortRequests()
+	m.resourceStarter.stop()
+}
+
+// AbortRequests answers the requests waiting for their resources with a 503, and cancels the requests being proxied.
+// servers embedding the middleware call it on shutdown, once their in-flight requests were not drained in time
+func (m *Middleware) AbortRequests() {
+	m.handler.abortRequests()
+}
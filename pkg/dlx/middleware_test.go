@@ -0,0 +1,255 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	resourcescalerMock "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+	scaler *resourcescalerMock.ResourceScaler
+}
+
+func (suite *MiddlewareTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *MiddlewareTestSuite) SetupTest() {
+	suite.scaler = &resourcescalerMock.ResourceScaler{}
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("127.0.0.1", nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+}
+
+func (suite *MiddlewareTestSuite) TestNextHandler() {
+	var servedTarget Target
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servedTarget, _ = TargetFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// the gateway resolves the resource by the first path segment
+	middleware, err := NewMiddleware(suite.logger,
+		WithResourceScaler(suite.scaler),
+		WithResourceResolver(func(req *http.Request) ([]string, []int, error) {
+			resourceName, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+			if resourceName == "" {
+				return nil, nil, errors.New("No resource in path")
+			}
+			return []string{resourceName}, nil, nil
+		}),
+		WithTargetPort(8080),
+		WithResourceReadinessTimeout(time.Second),
+		WithNextHandler(nextHandler))
+	suite.Require().NoError(err)
+
+	testResponse := httptest.NewRecorder()
+	middleware.ServeHTTP(testResponse, httptest.NewRequest("GET", "/my-function/invoke", nil))
+	suite.Require().Equal(http.StatusNoContent, testResponse.Code)
+	suite.Require().Equal("my-function", servedTarget.ResourceName)
+	suite.Require().Equal("127.0.0.1:8080", servedTarget.URL.Host)
+	suite.scaler.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+
+	// requests the resolver fails are rejected before reaching the next handler
+	testResponse = httptest.NewRecorder()
+	middleware.ServeHTTP(testResponse, httptest.NewRequest("GET", "/", nil))
+	suite.Require().Equal(http.StatusBadRequest, testResponse.Code)
+}
+
+func (suite *MiddlewareTestSuite) TestResolvedWeights() {
+	for _, testCase := range []struct {
+		name                string
+		resourceNames       []string
+		resourceWeights     []int
+		multiTargetStrategy scalertypes.MultiTargetStrategy
+		expectedStatusCode  int
+	}{
+		{
+			name:                "Valid weights",
+			resourceNames:       []string{"a", "b"},
+			resourceWeights:     []int{0, 100},
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			expectedStatusCode:  http.StatusNoContent,
+		}, {
+			name:                "Zero total weight",
+			resourceNames:       []string{"a", "b"},
+			resourceWeights:     []int{0, 0},
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			expectedStatusCode:  http.StatusNoContent,
+		}, {
+			name:                "Negative weight",
+			resourceNames:       []string{"a", "b"},
+			resourceWeights:     []int{-10, 20},
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			expectedStatusCode:  http.StatusNoContent,
+		}, {
+			name:                "More weights than names",
+			resourceNames:       []string{"a", "b"},
+			resourceWeights:     []int{0, 0, 100},
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			expectedStatusCode:  http.StatusNoContent,
+		}, {
+			name:                "Zero total weight with sticky sessions",
+			resourceNames:       []string{"a", "b"},
+			resourceWeights:     []int{0, 0},
+			multiTargetStrategy: scalertypes.MultiTargetStrategySticky,
+			expectedStatusCode:  http.StatusNoContent,
+		}, {
+			name:                "No names",
+			multiTargetStrategy: scalertypes.MultiTargetStrategyRandom,
+			expectedStatusCode:  http.StatusBadRequest,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			var servedTarget Target
+			middleware, err := NewMiddleware(suite.logger,
+				WithResourceScaler(suite.scaler),
+				WithResourceResolver(func(req *http.Request) ([]string, []int, error) {
+					return testCase.resourceNames, testCase.resourceWeights, nil
+				}),
+				WithDLXOptions(scalertypes.DLXOptions{
+					MultiTargetStrategy: testCase.multiTargetStrategy,
+					StickySession: scalertypes.StickySessionOptions{
+						KeySource: scalertypes.StickySessionKeySourceCookie,
+						KeyName:   "session",
+					},
+				}),
+				WithTargetPort(8080),
+				WithResourceReadinessTimeout(time.Second),
+				WithNextHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					servedTarget, _ = TargetFromContext(r.Context())
+					w.WriteHeader(http.StatusNoContent)
+				})))
+			suite.Require().NoError(err)
+
+			testRequest := httptest.NewRequest("GET", "/", nil)
+			testRequest.AddCookie(&http.Cookie{Name: "session", Value: "some-session"})
+			testResponse := httptest.NewRecorder()
+			middleware.ServeHTTP(testResponse, testRequest)
+			suite.Require().Equal(testCase.expectedStatusCode, testResponse.Code)
+			if testCase.expectedStatusCode == http.StatusNoContent {
+				suite.Require().Contains(testCase.resourceNames, servedTarget.ResourceName)
+			}
+		})
+	}
+}
+
+func (suite *MiddlewareTestSuite) TestStartStop() {
+	middleware, err := NewMiddleware(suite.logger,
+		WithResourceScaler(suite.scaler),
+		WithTargetNameHeader("X-Target"),
+		WithTargetPort(8080),
+		WithResourceReadinessTimeout(time.Second))
+	suite.Require().NoError(err)
+	suite.Require().NoError(middleware.Start())
+
+	middleware.Stop()
+
+	// wake-ups started after the stop end right away, instead of running on in the background
+	suite.Require().Error(middleware.resourceStarter.stopCtx.Err())
+}
+
+func (suite *MiddlewareTestSuite) TestProxy() {
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer targetServer.Close()
+	targetServerURL, err := url.Parse(targetServer.URL)
+	suite.Require().NoError(err)
+	targetPort, err := strconv.Atoi(targetServerURL.Port())
+	suite.Require().NoError(err)
+
+	middleware, err := NewMiddleware(suite.logger,
+		WithResourceScaler(suite.scaler),
+		WithTargetNameHeader("X-Target"),
+		WithTargetPort(targetPort),
+		WithResourceReadinessTimeout(time.Second))
+	suite.Require().NoError(err)
+
+	testRequest := httptest.NewRequest("GET", "/", nil)
+	testRequest.Header.Set("X-Target", "my-function")
+	testResponse := httptest.NewRecorder()
+	middleware.ServeHTTP(testResponse, testRequest)
+	suite.Require().Equal(http.StatusAccepted, testResponse.Code)
+}
+
+func (suite *MiddlewareTestSuite) TestValidation() {
+	for _, testCase := range []struct {
+		name              string
+		middlewareOptions []MiddlewareOption
+	}{
+		{
+			name:              "Missing resource scaler",
+			middlewareOptions: []MiddlewareOption{WithTargetNameHeader("X-Target")},
+		}, {
+			name:              "Missing resource resolver",
+			middlewareOptions: []MiddlewareOption{WithResourceScaler(suite.scaler)},
+		}, {
+			name: "Unknown sticky session key source",
+			middlewareOptions: []MiddlewareOption{
+				WithResourceScaler(suite.scaler),
+				WithDLXOptions(scalertypes.DLXOptions{
+					TargetNameHeader:    "X-Target",
+					MultiTargetStrategy: scalertypes.MultiTargetStrategySticky,
+					StickySession:       scalertypes.StickySessionOptions{KeySource: "clientIp"},
+				}),
+			},
+		}, {
+			name: "Missing sticky session key name",
+			middlewareOptions: []MiddlewareOption{
+				WithResourceScaler(suite.scaler),
+				WithDLXOptions(scalertypes.DLXOptions{
+					TargetNameHeader:    "X-Target",
+					MultiTargetStrategy: scalertypes.MultiTargetStrategySticky,
+					StickySession:       scalertypes.StickySessionOptions{KeySource: scalertypes.StickySessionKeySourceCookie},
+				}),
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := NewMiddleware(suite.logger, testCase.middlewareOptions...)
+			suite.Require().Error(err)
+		})
+	}
+}
+
+func TestMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
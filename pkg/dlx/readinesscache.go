@@ -0,0 +1,97 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuclio/logger"
+)
+
+// ReadinessCache holds the resources known to be ready, so that requests for them are proxied without starting them
+// again. An entry is invalidated when the ready endpoints of the resource service change, when proxying to the
+// resource fails, or when its TTL expires
+type ReadinessCache struct {
+	logger         logger.Logger
+	lock           sync.RWMutex
+	ttl            time.Duration
+	readyResources map[string]readinessCacheEntry
+}
+
+type readinessCacheEntry struct {
+	serviceName string
+	readyTime   time.Time
+}
+
+func NewReadinessCache(parentLogger logger.Logger, ttl time.Duration) *ReadinessCache {
+	return &ReadinessCache{
+		logger:         parentLogger.GetChild("readiness-cache"),
+		ttl:            ttl,
+		readyResources: map[string]readinessCacheEntry{},
+	}
+}
+
+// IsReady returns true if the resource was marked as ready within the cache TTL
+func (rc *ReadinessCache) IsReady(resourceName string) bool {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+
+	entry, found := rc.readyResources[resourceName]
+	return found && time.Since(entry.readyTime) < rc.ttl
+}
+
+// SetReady marks the resource, served by the given service, as ready
+func (rc *ReadinessCache) SetReady(resourceName string, serviceName string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	rc.readyResources[resourceName] = readinessCacheEntry{
+		serviceName: serviceName,
+		readyTime:   time.Now(),
+	}
+}
+
+// Invalidate removes the resource from the cache
+func (rc *ReadinessCache) Invalidate(resourceName string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	if _, found := rc.readyResources[resourceName]; found {
+		rc.logger.DebugWith("Invalidating resource readiness", "resourceName", resourceName)
+		delete(rc.readyResources, resourceName)
+	}
+}
+
+// InvalidateService removes all the resources served by the given service from the cache
+func (rc *ReadinessCache) InvalidateService(serviceName string) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	for resourceName, entry := range rc.readyResources {
+		if entry.serviceName == serviceName {
+			rc.logger.DebugWith("Invalidating resource readiness",
+				"resourceName", resourceName,
+				"serviceName", serviceName)
+			delete(rc.readyResources, resourceName)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultReadinessProbeInterval = time.Second
+	defaultReadinessProbeTimeout  = time.Second
+)
+
+// readinessProber polls a resource service until it accepts requests, as a scaled up resource may be reported ready
+// by the scaler before its service accepts connections
+type readinessProber struct {
+	logger     logger.Logger
+	probeType  scalertypes.ReadinessProbeType
+	path       string
+	port       int
+	interval   time.Duration
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+func newReadinessProber(parentLogger logger.Logger,
+	options scalertypes.ReadinessProbeOptions,
+	targetPort int) (*readinessProber, error) {
+	if !options.Enabled() {
+		return nil, nil
+	}
+	if options.Type != scalertypes.ReadinessProbeTypeHTTP && options.Type != scalertypes.ReadinessProbeTypeTCP {
+		return nil, errors.Errorf("Unsupported readiness probe type: %s", options.Type)
+	}
+
+	rp := &readinessProber{
+		logger:    parentLogger.GetChild("readiness-prober"),
+		probeType: options.Type,
+		path:      strings.TrimPrefix(options.Path, "/"),
+		port:      options.Port,
+		interval:  options.Interval.Duration,
+		timeout:   options.Timeout.Duration,
+	}
+	if rp.port == 0 {
+		rp.port = targetPort
+	}
+	if rp.interval == 0 {
+		rp.interval = defaultReadinessProbeInterval
+	}
+	if rp.timeout == 0 {
+		rp.timeout = defaultReadinessProbeTimeout
+	}
+	rp.httpClient = &http.Client{
+		Timeout: rp.timeout,
+
+		// a redirect is a valid response of a ready service, there is no need to follow it
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return rp, nil
+}
+
+// waitReady probes the service until it succeeds or the context is done
+func (rp *readinessProber) waitReady(ctx context.Context, serviceName string) error {
+	address := net.JoinHostPort(serviceName, strconv.Itoa(rp.port))
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+
+	for attempt := 1; ; attempt++ {
+		err := rp.probe(ctx, address)
+		if err == nil {
+			rp.logger.DebugWithCtx(ctx, "Service is ready",
+				"address", address,
+				"attempts", attempt)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "Service %s did not become ready after %d attempts", address, attempt)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rp *readinessProber) probe(ctx context.Context, address string) error {
+	if rp.probeType == scalertypes.ReadinessProbeTypeTCP {
+		dialer := net.Dialer{Timeout: rp.timeout}
+		connection, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return errors.Wrap(err, "Failed to connect to service")
+		}
+		return connection.Close()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s/%s", address, rp.path), nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create probe request")
+	}
+
+	response, err := rp.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send probe request")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("Probe request failed with status %d", response.StatusCode)
+	}
+
+	return nil
+}
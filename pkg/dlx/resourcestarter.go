@@ -24,6 +24,7 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/v3io/scaler/pkg/scalertypes"
@@ -34,54 +35,395 @@ import (
 
 type responseChannel chan ResourceStatusResult
 
+var ErrWarmResourcesBudgetExceeded = errors.New("Warm resources budget exceeded")
+var ErrTooManyWaitingRequests = errors.New("Too many requests waiting for resources to start")
+var ErrRequestAborted = errors.New("Request aborted, as the DLX is shutting down")
+var ErrResourceWaking = errors.New("Resource is still waking up")
+
+const defaultWarmResourcesBudgetPollInterval = 5 * time.Second
+
+// how long a sink deleted after rejecting its resource start keeps answering the requests that already fetched it
+const rejectedResourceSinkDrainPeriod = time.Second
+
+// the amount of resources whose wake-up status is kept, as resource names may come from requests and are not bounded
+const defaultMaxWakeStatuses = 10000
+
 type ResourceStarter struct {
 	logger                   logger.Logger
 	namespace                string
 	resourceSinksMap         sync.Map
 	resourceReadinessTimeout time.Duration
 	scaler                   scalertypes.ResourceScaler
+	waitForDependencies      bool
+
+	warmResourcesBudget             scalertypes.WarmResourcesBudget
+	warmResourcesBudgetPollInterval time.Duration
+
+	// resource name -> amount of requests waiting for the resource to start
+	waitingRequestsLock           sync.Mutex
+	waitingRequestsMap            sync.Map
+	totalWaitingRequests          atomic.Int64
+	maxWaitingRequestsPerResource int64
+	maxWaitingRequests            int64
+	wakeRequestsPerReplica        int
+	maxWakeReplicas               int
+
+	// resource name -> resourceWakeStatus. once there are too many, the status of the wake-up that ended the longest
+	// ago is evicted (0 keeps them all)
+	wakeStatusesLock  sync.Mutex
+	wakeStatusesMap   sync.Map
+	wakeStatusesCount int
+	maxWakeStatuses   int
+
+	// when set, requests for resources known to be ready are answered without starting them again
+	readinessCache *ReadinessCache
+
+	// when set, a scaled up resource is ready only once its service passes the probe
+	readinessProber *readinessProber
+
+	// when set, called in the background once a resource started successfully
+	resourceStartedCallback func(resourceName string)
+
+	// canceled on stop, which ends the wake-ups in progress
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
 }
 
 type ResourceStatusResult struct {
 	ResourceName string
 	Status       int
 	Error        error
+
+	// when set, the time after which the request may be retried
+	RetryAfter time.Duration
+}
+
+// resourceWakeStatus holds the timings of the current, or last, wake-up of a resource
+type resourceWakeStatus struct {
+	startTime time.Time
+
+	// zero while the resource is waking up
+	endTime time.Time
+
+	// duration of the last successful wake-up
+	lastWakeDuration time.Duration
+
+	// the error the wake-up ended with, if it failed
+	err error
 }
 
 func NewResourceStarter(parentLogger logger.Logger,
 	scaler scalertypes.ResourceScaler,
 	namespace string,
-	resourceReadinessTimeout time.Duration) (*ResourceStarter, error) {
+	resourceReadinessTimeout time.Duration,
+	waitForDependencies bool,
+	warmResourcesBudget scalertypes.WarmResourcesBudget,
+	wakeRequestsPerReplica int,
+	maxWakeReplicas int,
+	maxWaitingRequestsPerResource int,
+	maxWaitingRequests int,
+	readinessCache *ReadinessCache,
+	readinessProbe scalertypes.ReadinessProbeOptions,
+	targetPort int) (*ResourceStarter, error) {
+	childLogger := parentLogger.GetChild("resource-starter")
+	prober, err := newReadinessProber(childLogger, readinessProbe, targetPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create readiness prober")
+	}
+
+	stopCtx, stopCancel := context.WithCancel(context.Background())
 	fs := &ResourceStarter{
-		logger:                   parentLogger.GetChild("resource-starter"),
-		resourceSinksMap:         sync.Map{},
-		namespace:                namespace,
-		resourceReadinessTimeout: resourceReadinessTimeout,
-		scaler:                   scaler,
+		logger:                          childLogger,
+		resourceSinksMap:                sync.Map{},
+		namespace:                       namespace,
+		resourceReadinessTimeout:        resourceReadinessTimeout,
+		scaler:                          scaler,
+		waitForDependencies:             waitForDependencies,
+		warmResourcesBudget:             warmResourcesBudget,
+		warmResourcesBudgetPollInterval: defaultWarmResourcesBudgetPollInterval,
+		waitingRequestsMap:              sync.Map{},
+		wakeRequestsPerReplica:          wakeRequestsPerReplica,
+		maxWakeReplicas:                 maxWakeReplicas,
+		maxWaitingRequestsPerResource:   int64(maxWaitingRequestsPerResource),
+		maxWaitingRequests:              int64(maxWaitingRequests),
+		wakeStatusesMap:                 sync.Map{},
+		maxWakeStatuses:                 defaultMaxWakeStatuses,
+		readinessCache:                  readinessCache,
+		readinessProber:                 prober,
+		stopCtx:                         stopCtx,
+		stopCancel:                      stopCancel,
 	}
 	return fs, nil
 }
 
+// stop ends the wake-ups in progress, failing the requests still waiting for them
+func (r *ResourceStarter) stop() {
+	if r.stopCancel != nil {
+		r.stopCancel()
+	}
+}
+
 func (r *ResourceStarter) handleResourceStart(originalTarget string, handlerResponseChannel responseChannel) {
-	r.getOrCreateResourceSink(originalTarget) <- handlerResponseChannel
+	r.handleResourceStartUntil(originalTarget, handlerResponseChannel, nil)
+}
+
+// handleResourceStartUntil is like handleResourceStart, but once the wait is done the request is no longer counted as
+// waiting, and the resource is left to start in the background (a nil wait channel waits for the start)
+func (r *ResourceStarter) handleResourceStartUntil(originalTarget string,
+	handlerResponseChannel responseChannel,
+	waitDone <-chan struct{}) {
+	if r.isResourceReady(originalTarget) {
+		handlerResponseChannel <- ResourceStatusResult{
+			ResourceName: originalTarget,
+			Status:       http.StatusOK,
+		}
+		return
+	}
+
+	resourceWaitingRequests := r.addWaitingRequest(originalTarget)
+	totalWaitingRequests := r.totalWaitingRequests.Add(1)
+	defer r.removeWaitingRequest(originalTarget)
+	defer r.totalWaitingRequests.Add(-1)
+
+	if statusResult := r.checkWaitingRequestsLimits(originalTarget,
+		resourceWaitingRequests,
+		totalWaitingRequests); statusResult != nil {
+		handlerResponseChannel <- *statusResult
+		return
+	}
+
+	// the sink accepts the response channel only once the resource start is done, so until then the request is waiting
+	select {
+	case r.getOrCreateResourceSink(originalTarget, true) <- handlerResponseChannel:
+	case <-waitDone:
+	}
+}
+
+// handleBackgroundResourceStart starts a resource no request is waiting for, so it is not counted as a waiting request
+func (r *ResourceStarter) handleBackgroundResourceStart(resourceName string) {
+	if r.isResourceReady(resourceName) {
+		return
+	}
+
+	r.waitBackgroundResourceStart(resourceName, r.getOrCreateResourceSink(resourceName, true))
+}
+
+// triggerBackgroundResourceStart starts a resource in the background, like handleBackgroundResourceStart, but returns
+// once the wake-up is started rather than once it is done, so that the wake-up status already reports it
+func (r *ResourceStarter) triggerBackgroundResourceStart(resourceName string) {
+	if r.isResourceReady(resourceName) {
+		return
+	}
+
+	go r.waitBackgroundResourceStart(resourceName, r.getOrCreateResourceSink(resourceName, true))
+}
+
+func (r *ResourceStarter) waitBackgroundResourceStart(resourceName string, resourceSinkChannel chan responseChannel) {
+	resourceResponseChannel := make(responseChannel, 1)
+	resourceSinkChannel <- resourceResponseChannel
+	if statusResult := <-resourceResponseChannel; statusResult.Error != nil {
+		r.logger.WarnWith("Failed to start resource in the background",
+			"resourceName", resourceName,
+			"err", errors.GetErrorStackString(statusResult.Error, 10))
+	}
+}
+
+// GetWaitingRequests returns the total amount of requests waiting for resources to start, and the amount per resource
+func (r *ResourceStarter) GetWaitingRequests() (int, map[string]int) {
+	waitingRequestsPerResource := map[string]int{}
+	r.waitingRequestsMap.Range(func(key, value interface{}) bool {
+		if waitingRequests := value.(*atomic.Int64).Load(); waitingRequests > 0 {
+			waitingRequestsPerResource[key.(string)] = int(waitingRequests)
+		}
+		return true
+	})
+
+	return int(r.totalWaitingRequests.Load()), waitingRequestsPerResource
+}
+
+// checkWaitingRequestsLimits returns a rejection result if the request would exceed the amount of requests allowed to
+// wait for the resource, or for all resources, to start
+func (r *ResourceStarter) checkWaitingRequestsLimits(resourceName string,
+	resourceWaitingRequests int64,
+	totalWaitingRequests int64) *ResourceStatusResult {
+	var status int
+	switch {
+	case r.maxWaitingRequestsPerResource > 0 && resourceWaitingRequests > r.maxWaitingRequestsPerResource:
+		status = http.StatusTooManyRequests
+	case r.maxWaitingRequests > 0 && totalWaitingRequests > r.maxWaitingRequests:
+		status = http.StatusServiceUnavailable
+	default:
+		return nil
+	}
+
+	retryAfter := r.getExpectedReadinessDuration(resourceName)
+	r.logger.WarnWith("Too many requests waiting for resources to start, rejecting request",
+		"resourceName", resourceName,
+		"resourceWaitingRequests", resourceWaitingRequests,
+		"totalWaitingRequests", totalWaitingRequests,
+		"retryAfter", retryAfter)
+
+	return &ResourceStatusResult{
+		ResourceName: resourceName,
+		Status:       status,
+		Error:        ErrTooManyWaitingRequests,
+		RetryAfter:   retryAfter,
+	}
+}
+
+// getExpectedReadinessDuration returns how long until the resource is expected to be ready, based on its last wake-up
+func (r *ResourceStarter) getExpectedReadinessDuration(resourceName string) time.Duration {
+	wakeStatus, found := r.getWakeStatus(resourceName)
+	if !found || !wakeStatus.endTime.IsZero() {
+		return time.Second
+	}
+
+	expectedWakeDuration := wakeStatus.lastWakeDuration
+	if expectedWakeDuration == 0 {
+		expectedWakeDuration = r.resourceReadinessTimeout
+	}
+
+	if remaining := expectedWakeDuration - time.Since(wakeStatus.startTime); remaining > time.Second {
+		return remaining
+	}
+	return time.Second
+}
+
+func (r *ResourceStarter) isResourceReady(resourceName string) bool {
+	return r.readinessCache != nil && r.readinessCache.IsReady(resourceName)
 }
 
-func (r *ResourceStarter) getOrCreateResourceSink(originalTarget string) chan responseChannel {
+func (r *ResourceStarter) setResourceReady(ctx context.Context, resourceName string) {
+	if r.readinessCache == nil {
+		return
+	}
+
+	// the service is what the endpoint slices are keyed by, so an unresolved resource cannot be invalidated - skip it
+	serviceName, err := r.scaler.ResolveServiceName(scalertypes.Resource{Name: resourceName})
+	if err != nil {
+		r.logger.WarnWithCtx(ctx, "Failed to resolve service name, not caching resource readiness",
+			"resourceName", resourceName,
+			"err", errors.GetErrorStackString(err, 10))
+		return
+	}
+
+	r.readinessCache.SetReady(resourceName, serviceName)
+}
+
+func (r *ResourceStarter) invalidateResourceReadiness(resourceName string) {
+	if r.readinessCache != nil {
+		r.readinessCache.Invalidate(resourceName)
+	}
+}
+
+func (r *ResourceStarter) getWakeStatus(resourceName string) (resourceWakeStatus, bool) {
+	wakeStatus, found := r.wakeStatusesMap.Load(resourceName)
+	if !found {
+		return resourceWakeStatus{}, false
+	}
+	return wakeStatus.(resourceWakeStatus), true
+}
+
+// getWakeEndTime returns when the last wake-up of the resource ended, or zero if it was never woken up
+func (r *ResourceStarter) getWakeEndTime(resourceName string) time.Time {
+	wakeStatus, _ := r.getWakeStatus(resourceName)
+	return wakeStatus.endTime
+}
+
+func (r *ResourceStarter) setWakeStarted(resourceName string) {
+	r.wakeStatusesLock.Lock()
+	defer r.wakeStatusesLock.Unlock()
+
+	wakeStatus, found := r.getWakeStatus(resourceName)
+	r.storeWakeStatus(resourceName, resourceWakeStatus{
+		startTime:        time.Now(),
+		lastWakeDuration: wakeStatus.lastWakeDuration,
+	}, found)
+}
+
+func (r *ResourceStarter) setWakeEnded(resourceName string, err error) {
+	r.wakeStatusesLock.Lock()
+	defer r.wakeStatusesLock.Unlock()
+
+	wakeStatus, found := r.getWakeStatus(resourceName)
+	wakeStatus.endTime = time.Now()
+	wakeStatus.err = err
+	if err == nil {
+		wakeStatus.lastWakeDuration = wakeStatus.endTime.Sub(wakeStatus.startTime)
+	}
+	r.storeWakeStatus(resourceName, wakeStatus, found)
+}
+
+// storeWakeStatus stores the wake-up status of the resource, making room for it if it is new. must be called with the
+// wake statuses lock held
+func (r *ResourceStarter) storeWakeStatus(resourceName string, wakeStatus resourceWakeStatus, found bool) {
+	if !found {
+		if r.maxWakeStatuses > 0 && r.wakeStatusesCount >= r.maxWakeStatuses {
+			r.evictOldestWakeStatus()
+		}
+		r.wakeStatusesCount++
+	}
+	r.wakeStatusesMap.Store(resourceName, wakeStatus)
+}
+
+// evictOldestWakeStatus deletes the status of the wake-up that ended the longest ago. wake-ups in progress are kept
+func (r *ResourceStarter) evictOldestWakeStatus() {
+	var oldestResourceName string
+	var oldestEndTime time.Time
+	r.wakeStatusesMap.Range(func(key, value interface{}) bool {
+		endTime := value.(resourceWakeStatus).endTime
+		if !endTime.IsZero() && (oldestEndTime.IsZero() || endTime.Before(oldestEndTime)) {
+			oldestResourceName = key.(string)
+			oldestEndTime = endTime
+		}
+		return true
+	})
+
+	if oldestResourceName != "" {
+		r.wakeStatusesMap.Delete(oldestResourceName)
+		r.wakeStatusesCount--
+	}
+}
+
+// handleDependencyStart starts a resource on behalf of a dependent resource. the dependent resource already resolved
+// the whole dependency tree, so the dependency's own dependencies are not started again (which also breaks cycles)
+func (r *ResourceStarter) handleDependencyStart(dependencyName string, dependentResponseChannel responseChannel) {
+	if r.isResourceReady(dependencyName) {
+		dependentResponseChannel <- ResourceStatusResult{
+			ResourceName: dependencyName,
+			Status:       http.StatusOK,
+		}
+		return
+	}
+
+	r.getOrCreateResourceSink(dependencyName, false) <- dependentResponseChannel
+}
+
+func (r *ResourceStarter) getOrCreateResourceSink(originalTarget string, startDependencies bool) chan responseChannel {
 	resourceSink, found := r.resourceSinksMap.LoadOrStore(originalTarget, make(chan responseChannel))
 	resourceSinkChannel := resourceSink.(chan responseChannel)
 	if !found {
-		ctx := context.Background()
+
+		// resource starters that were not created by NewResourceStarter are never stopped
+		ctx := r.stopCtx
+		if ctx == nil {
+			ctx = context.Background()
+		}
 		r.logger.DebugWithCtx(ctx, "Starting resource sink", "target", originalTarget)
+		r.setWakeStarted(originalTarget)
 
 		// for the next requests coming in
 		// start the resource and get ready to listen on resource sink channel
-		go r.startResource(ctx, resourceSinkChannel, originalTarget)
+		go r.startResource(ctx, resourceSinkChannel, originalTarget, startDependencies)
 	}
 
 	return resourceSinkChannel
 }
 
-func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel chan responseChannel, target string) {
+func (r *ResourceStarter) startResource(ctx context.Context,
+	resourceSinkChannel chan responseChannel,
+	target string,
+	startDependencies bool) {
 	var resultStatus ResourceStatusResult
 
 	// simple for now
@@ -99,11 +441,24 @@ func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel
 	waitResourceReadinessCtx, cancelFuncTimeout := context.WithTimeout(ctx, 15*time.Minute)
 	defer cancelFuncTimeout()
 
-	go r.waitResourceReadiness(waitResourceReadinessCtx,
-		scalertypes.Resource{Name: resourceName,
+	knownResources := r.getKnownResources(ctx)
 
-			// TODO: get a argument or it won't know which function on what namespace it should wake up
-			Namespace: r.namespace},
+	var dependencyNames []string
+	if startDependencies {
+		dependencyNames = r.resolveDependencies(ctx, resourceName, knownResources)
+	}
+
+	// dependencies are started in parallel to the resource itself, through their own sinks
+	dependenciesResponseChannel := make(responseChannel, len(dependencyNames))
+	for _, dependencyName := range dependencyNames {
+		go r.handleDependencyStart(dependencyName, dependenciesResponseChannel)
+	}
+
+	go r.waitResourceReadiness(waitResourceReadinessCtx,
+		r.resolveResourcesToStart(ctx, resourceName, knownResources),
+		knownResources,
+		dependenciesResponseChannel,
+		len(dependencyNames),
 		resourceReadyChannel)
 
 	select {
@@ -122,6 +477,7 @@ func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel
 		logArgs := []interface{}{
 			"resourceName", resourceName,
 			"target", target,
+			"waitingRequests", r.getResourceWaitingRequests(resourceName),
 		}
 		if err != nil {
 			logArgs = append(logArgs, "err", errors.GetErrorStackString(err, 10))
@@ -136,6 +492,12 @@ func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel
 				Status:       http.StatusOK,
 				ResourceName: resourceName,
 			}
+		} else if errors.Is(err, ErrWarmResourcesBudgetExceeded) {
+			resultStatus = ResourceStatusResult{
+				Status:       http.StatusServiceUnavailable,
+				ResourceName: resourceName,
+				Error:        err,
+			}
 		} else {
 			resultStatus = ResourceStatusResult{
 				Status:       http.StatusInternalServerError,
@@ -146,6 +508,22 @@ func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel
 
 	}
 
+	r.setWakeEnded(resourceName, resultStatus.Error)
+	if resultStatus.Error == nil {
+		r.setResourceReady(ctx, resourceName)
+		if r.resourceStartedCallback != nil {
+			go r.resourceStartedCallback(resourceName)
+		}
+	}
+
+	// do not hold on to the rejection, the budget may free up for the next request. the sink is deleted right away so
+	// that the next requests start over, and only the requests already waiting on it are answered with the rejection
+	if errors.Is(resultStatus.Error, ErrWarmResourcesBudgetExceeded) {
+		r.deleteResourceSink(resourceName)
+		r.rejectWaitingRequests(resourceSinkChannel, resultStatus)
+		return
+	}
+
 	// now handle all pending requests for a minute
 	tc := time.After(1 * time.Minute)
 	for {
@@ -159,24 +537,344 @@ func (r *ResourceStarter) startResource(ctx context.Context, resourceSinkChannel
 	}
 }
 
+// rejectWaitingRequests answers the requests waiting on a deleted sink. a request may have fetched the sink just before
+// it was deleted, so the sink keeps answering for a short drain period rather than leaving such a request hanging
+func (r *ResourceStarter) rejectWaitingRequests(resourceSinkChannel chan responseChannel, resultStatus ResourceStatusResult) {
+	drainTimer := time.NewTimer(rejectedResourceSinkDrainPeriod)
+	defer drainTimer.Stop()
+
+	for {
+		select {
+		case channel := <-resourceSinkChannel:
+			channel <- resultStatus
+		case <-drainTimer.C:
+			return
+		}
+	}
+}
+
+// waitResourceProbe waits for the service of the resource to pass the readiness probe, up to the readiness timeout
+func (r *ResourceStarter) waitResourceProbe(ctx context.Context, resourceName string) error {
+	serviceName, err := r.scaler.ResolveServiceName(scalertypes.Resource{Name: resourceName})
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve service name")
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, r.resourceReadinessTimeout)
+	defer cancel()
+
+	if err := r.readinessProber.waitReady(probeCtx, serviceName); err != nil {
+		return errors.Wrap(err, "Resource failed readiness probe")
+	}
+
+	return nil
+}
+
+// waitResourceReadiness scales up the given resources, the first of which is the requested resource, and if configured
+// to do so, waits for its dependencies to be ready as well
 func (r *ResourceStarter) waitResourceReadiness(ctx context.Context,
-	resource scalertypes.Resource,
+	resources []scalertypes.Resource,
+	knownResources []scalertypes.Resource,
+	dependenciesResponseChannel responseChannel,
+	dependenciesAmount int,
 	resourceReadyChannel chan error) {
 
-	err := r.scaler.SetScaleCtx(ctx, []scalertypes.Resource{resource}, 1)
+	wakeReplicas, maxWakeReplicas := r.getWakeReplicas(resources[0].Name, knownResources)
+
+	err := r.waitWarmResourcesBudget(ctx, resources, knownResources)
+	if err == nil {
+		err = r.scaler.SetScaleCtx(ctx, resources, wakeReplicas)
+	}
+	if err == nil {
+		r.scaleForWaitingRequests(ctx, resources, wakeReplicas, maxWakeReplicas)
+	}
+	if err == nil && r.readinessProber != nil {
+		err = r.waitResourceProbe(ctx, resources[0].Name)
+	}
+	if err == nil && r.waitForDependencies {
+		err = r.waitDependenciesReadiness(ctx, dependenciesResponseChannel, dependenciesAmount)
+	}
 
 	// callee decided to cancel, the resourceReadyChannel is already closed,
 	// so we can just return without sending anything
 	if ctx.Err() != nil {
 		r.logger.WarnWithCtx(ctx,
 			"Wait resource readiness canceled",
-			"resourceName", resource.Name,
+			"resourceName", resources[0].Name,
 			"err", ctx.Err())
 		return
 	}
 	resourceReadyChannel <- err
 }
 
+// getWakeReplicas returns the amount of replicas to wake the resource with, and the maximal amount of replicas it may
+// be scaled to while requests are queued for it
+func (r *ResourceStarter) getWakeReplicas(resourceName string, knownResources []scalertypes.Resource) (int, int) {
+	wakeReplicas, maxWakeReplicas := 1, r.maxWakeReplicas
+	if resource := r.findKnownResource(resourceName, knownResources); resource != nil {
+		wakeReplicas = resource.GetWakeReplicas()
+		if resource.MaxWakeReplicas > 0 {
+			maxWakeReplicas = resource.MaxWakeReplicas
+		}
+	}
+
+	if maxWakeReplicas < wakeReplicas {
+		maxWakeReplicas = wakeReplicas
+	}
+
+	return wakeReplicas, maxWakeReplicas
+}
+
+// scaleForWaitingRequests asks for more replicas, in proportion to the amount of requests queued for the resource
+// while it was waking up. the scale up is done in the background, as the woken replicas can already serve requests
+func (r *ResourceStarter) scaleForWaitingRequests(ctx context.Context,
+	resources []scalertypes.Resource,
+	wakeReplicas int,
+	maxWakeReplicas int) {
+	if r.wakeRequestsPerReplica <= 0 || maxWakeReplicas <= wakeReplicas {
+		return
+	}
+
+	waitingRequests := int(r.getResourceWaitingRequests(resources[0].Name))
+	replicas := (waitingRequests + r.wakeRequestsPerReplica - 1) / r.wakeRequestsPerReplica
+	if replicas > maxWakeReplicas {
+		replicas = maxWakeReplicas
+	}
+	if replicas <= wakeReplicas {
+		return
+	}
+
+	r.logger.InfoWithCtx(ctx,
+		"Scaling up woken resource for waiting requests",
+		"resourceName", resources[0].Name,
+		"waitingRequests", waitingRequests,
+		"replicas", replicas)
+
+	go func() {
+		scaleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.resourceReadinessTimeout)
+		defer cancel()
+
+		if err := r.scaler.SetScaleCtx(scaleCtx, resources, replicas); err != nil {
+			r.logger.WarnWithCtx(ctx,
+				"Failed to scale up woken resource for waiting requests",
+				"resourceName", resources[0].Name,
+				"replicas", replicas,
+				"err", errors.GetErrorStackString(err, 10))
+		}
+	}()
+}
+
+// addWaitingRequest counts a request waiting for the resource to start, and returns the amount of requests waiting for it
+func (r *ResourceStarter) addWaitingRequest(resourceName string) int64 {
+	r.waitingRequestsLock.Lock()
+	defer r.waitingRequestsLock.Unlock()
+
+	waitingRequests, _ := r.waitingRequestsMap.LoadOrStore(resourceName, &atomic.Int64{})
+	return waitingRequests.(*atomic.Int64).Add(1)
+}
+
+// removeWaitingRequest stops counting a request waiting for the resource to start. the counters of resources no request
+// waits for are deleted, as resource names may come from requests and are not bounded
+func (r *ResourceStarter) removeWaitingRequest(resourceName string) {
+	r.waitingRequestsLock.Lock()
+	defer r.waitingRequestsLock.Unlock()
+
+	waitingRequests, found := r.waitingRequestsMap.Load(resourceName)
+	if !found {
+		return
+	}
+	if waitingRequests.(*atomic.Int64).Add(-1) <= 0 {
+		r.waitingRequestsMap.Delete(resourceName)
+	}
+}
+
+func (r *ResourceStarter) getResourceWaitingRequests(resourceName string) int64 {
+	waitingRequests, found := r.waitingRequestsMap.Load(resourceName)
+	if !found {
+		return 0
+	}
+	return waitingRequests.(*atomic.Int64).Load()
+}
+
+func (r *ResourceStarter) waitDependenciesReadiness(ctx context.Context,
+	dependenciesResponseChannel responseChannel,
+	dependenciesAmount int) error {
+	for range dependenciesAmount {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "Canceled while waiting for dependencies")
+		case statusResult := <-dependenciesResponseChannel:
+			if statusResult.Error != nil {
+				return errors.Wrapf(statusResult.Error, "Failed to start dependency %s", statusResult.ResourceName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitWarmResourcesBudget returns once waking the given resources keeps the warm resources within the budget. if the
+// budget is exceeded it either fails or, if configured to queue wake-ups, waits for the budget to free up
+func (r *ResourceStarter) waitWarmResourcesBudget(ctx context.Context,
+	resources []scalertypes.Resource,
+	knownResources []scalertypes.Resource) error {
+	if !r.warmResourcesBudget.Enabled() {
+		return nil
+	}
+
+	scopeKey := r.warmResourcesBudget.GetScopeKey(scalertypes.Resource{Namespace: r.namespace})
+	for {
+		wakeCost := 0
+		for _, resource := range resources {
+			knownResource := r.findKnownResource(resource.Name, knownResources)
+			if knownResource == nil {
+				wakeCost++
+			} else if !knownResource.IsWarm() {
+				wakeCost += knownResource.GetCost()
+			}
+		}
+
+		warmCost := r.warmResourcesBudget.GetWarmCost(knownResources, scopeKey)
+		if wakeCost == 0 || warmCost+wakeCost <= r.warmResourcesBudget.MaxWarmResources {
+			return nil
+		}
+
+		if r.warmResourcesBudget.ExceededPolicy != scalertypes.WarmResourcesBudgetExceededPolicyQueue {
+			return errors.Wrapf(ErrWarmResourcesBudgetExceeded,
+				"Waking %s would exceed the warm resources budget (warm: %d, wake: %d, max: %d)",
+				resources[0].Name,
+				warmCost,
+				wakeCost,
+				r.warmResourcesBudget.MaxWarmResources)
+		}
+
+		r.logger.DebugWithCtx(ctx,
+			"Warm resources budget exceeded, waiting for it to free up",
+			"resourceName", resources[0].Name,
+			"warmCost", warmCost,
+			"wakeCost", wakeCost,
+			"maxWarmResources", r.warmResourcesBudget.MaxWarmResources)
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "Canceled while waiting for warm resources budget")
+		case <-time.After(r.warmResourcesBudgetPollInterval):
+		}
+
+		if knownResources = r.getKnownResources(ctx); knownResources == nil {
+			return errors.New("Failed to get resources while waiting for warm resources budget")
+		}
+	}
+}
+
+// getKnownResources returns the resources known to the resource scaler, or nil if they could not be listed.
+// the metadata of these resources (e.g. scale group, dependencies) is used to decide what to start along the resource
+func (r *ResourceStarter) getKnownResources(ctx context.Context) []scalertypes.Resource {
+	knownResources, err := r.scaler.GetResources()
+	if err != nil {
+		r.logger.WarnWithCtx(ctx,
+			"Failed to get resources, starting resource without its metadata",
+			"err", errors.GetErrorStackString(err, 10))
+		return nil
+	}
+
+	return knownResources
+}
+
+func (r *ResourceStarter) findKnownResource(resourceName string,
+	knownResources []scalertypes.Resource) *scalertypes.Resource {
+	for idx, knownResource := range knownResources {
+		if knownResource.Name == resourceName && r.isInNamespace(knownResource) {
+			return &knownResources[idx]
+		}
+	}
+
+	return nil
+}
+
+// resolveDependencies returns the names of all the resources the given resource transitively depends on
+func (r *ResourceStarter) resolveDependencies(ctx context.Context,
+	resourceName string,
+	knownResources []scalertypes.Resource) []string {
+	var dependencyNames []string
+	visited := map[string]bool{resourceName: true}
+	pending := []string{resourceName}
+
+	for len(pending) > 0 {
+		currentResourceName := pending[0]
+		pending = pending[1:]
+
+		currentResource := r.findKnownResource(currentResourceName, knownResources)
+		if currentResource == nil {
+			continue
+		}
+
+		for _, dependencyName := range currentResource.Dependencies {
+			if visited[dependencyName] {
+				continue
+			}
+			visited[dependencyName] = true
+			dependencyNames = append(dependencyNames, dependencyName)
+			pending = append(pending, dependencyName)
+		}
+	}
+
+	if len(dependencyNames) > 0 {
+		r.logger.InfoWithCtx(ctx,
+			"Starting resource dependencies",
+			"resourceName", resourceName,
+			"dependencies", dependencyNames,
+			"waitForDependencies", r.waitForDependencies)
+	}
+
+	return dependencyNames
+}
+
+// resolveResourcesToStart returns the requested resource followed by the other members of its scale group, if any
+func (r *ResourceStarter) resolveResourcesToStart(ctx context.Context,
+	resourceName string,
+	knownResources []scalertypes.Resource) []scalertypes.Resource {
+	resourcesToStart := []scalertypes.Resource{
+		{
+			Name: resourceName,
+
+			// TODO: get a argument or it won't know which function on what namespace it should wake up
+			Namespace: r.namespace,
+		},
+	}
+
+	resource := r.findKnownResource(resourceName, knownResources)
+	if resource == nil || resource.ScaleGroup == "" {
+		return resourcesToStart
+	}
+	scaleGroup := resource.ScaleGroup
+
+	for _, knownResource := range knownResources {
+		if knownResource.ScaleGroup != scaleGroup ||
+			knownResource.Name == resourceName ||
+			!r.isInNamespace(knownResource) {
+			continue
+		}
+
+		resourcesToStart = append(resourcesToStart, scalertypes.Resource{
+			Name:      knownResource.Name,
+			Namespace: r.namespace,
+		})
+	}
+
+	r.logger.InfoWithCtx(ctx,
+		"Starting resource along with its scale group",
+		"resourceName", resourceName,
+		"scaleGroup", scaleGroup,
+		"resources", resourcesToStart)
+
+	return resourcesToStart
+}
+
+func (r *ResourceStarter) isInNamespace(resource scalertypes.Resource) bool {
+	return resource.Namespace == "" || resource.Namespace == r.namespace
+}
+
 func (r *ResourceStarter) deleteResourceSink(resourceName string) {
 	r.resourceSinksMap.Delete(resourceName)
 }
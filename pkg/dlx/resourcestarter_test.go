@@ -20,13 +20,19 @@ such restriction.
 package dlx
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	mockresourcescaler "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
 
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
@@ -61,6 +67,9 @@ func (suite *resourceStarterTest) TestDlxMultipleRequests() {
 	suite.mocker.
 		On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{}, nil)
 
 	for i := 0; i < 200; i++ {
 		wg.Add(1)
@@ -81,6 +90,9 @@ func (suite *resourceStarterTest) TestDlxMultipleRequestsSameTarget() {
 	suite.mocker.
 		On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
 		Return(nil)
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{}, nil)
 
 	for i := 0; i < 4; i++ {
 		wg.Add(1)
@@ -98,6 +110,572 @@ func (suite *resourceStarterTest) TestDlxMultipleRequestsSameTarget() {
 	suite.Require().True(suite.mocker.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1))
 }
 
+func (suite *resourceStarterTest) TestDlxStartsScaleGroupMembers() {
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{
+			{Name: "test-canary", Namespace: "default", ScaleGroup: "test-group"},
+			{Name: "test-other", Namespace: "default"},
+			{Name: "test-primary", Namespace: "default", ScaleGroup: "test-group"},
+			{Name: "test-other-namespace", Namespace: "other", ScaleGroup: "test-group"},
+		}, nil)
+	suite.mocker.
+		On("SetScaleCtx", mock.Anything, []scalertypes.Resource{
+			{Name: "test-primary", Namespace: "default"},
+			{Name: "test-canary", Namespace: "default"},
+		}, 1).
+		Return(nil)
+
+	ch := make(responseChannel)
+	suite.functionStarter.handleResourceStart("test-primary", ch)
+	r := <-ch
+	suite.Require().Equal(http.StatusOK, r.Status)
+	suite.mocker.AssertExpectations(suite.T())
+}
+
+func (suite *resourceStarterTest) TestDlxStartsDependencies() {
+	for _, testCase := range []struct {
+		name                string
+		waitForDependencies bool
+		dependencyErr       error
+		dependencyDelay     time.Duration
+		expectedStatus      int
+	}{
+		{
+			name:            "Do not wait for slow dependencies",
+			dependencyDelay: 5 * time.Second,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:                "Wait for dependencies",
+			waitForDependencies: true,
+			dependencyDelay:     100 * time.Millisecond,
+			expectedStatus:      http.StatusOK,
+		},
+		{
+			name:                "Wait for dependencies, dependency fails",
+			waitForDependencies: true,
+			dependencyErr:       errors.New("failed to start dependency"),
+			expectedStatus:      http.StatusInternalServerError,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+			suite.functionStarter.waitForDependencies = testCase.waitForDependencies
+
+			// frontend -> backend -> database -> frontend, the cycle should not deadlock
+			suite.mocker.
+				On("GetResources").
+				Return([]scalertypes.Resource{
+					{Name: "frontend", Dependencies: []string{"backend"}},
+					{Name: "backend", Dependencies: []string{"database"}},
+					{Name: "database", Dependencies: []string{"frontend"}},
+				}, nil)
+			suite.mocker.
+				On("SetScaleCtx", mock.Anything, suite.resourcesNamed("frontend"), 1).
+				Return(nil)
+			dependenciesStarted := atomic.Int32{}
+			for _, dependencyName := range []string{"backend", "database"} {
+				suite.mocker.
+					On("SetScaleCtx", mock.Anything, suite.resourcesNamed(dependencyName), 1).
+					Run(func(args mock.Arguments) {
+						dependenciesStarted.Add(1)
+					}).
+					After(testCase.dependencyDelay).
+					Return(testCase.dependencyErr)
+			}
+
+			ch := make(responseChannel)
+			suite.functionStarter.handleResourceStart("frontend", ch)
+
+			select {
+			case r := <-ch:
+				suite.Require().Equal(testCase.expectedStatus, r.Status)
+			case <-time.After(testCase.dependencyDelay / 2):
+				suite.Require().True(testCase.waitForDependencies, "Should not have waited for dependencies")
+				r := <-ch
+				suite.Require().Equal(testCase.expectedStatus, r.Status)
+			}
+
+			// all the dependencies must have been started
+			suite.Require().Eventually(func() bool {
+				return dependenciesStarted.Load() == 2
+			}, 10*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func (suite *resourceStarterTest) TestDlxWarmResourcesBudget() {
+	scaledToZeroEvent := scalertypes.ScaleToZeroCompletedScaleEvent
+	warmResources := []scalertypes.Resource{
+		{Name: "warm", Namespace: "default"},
+		{Name: "cold", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+	}
+	freedResources := []scalertypes.Resource{
+		{Name: "warm", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+		{Name: "cold", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+	}
+
+	for _, testCase := range []struct {
+		name           string
+		policy         scalertypes.WarmResourcesBudgetExceededPolicy
+		resourceName   string
+		expectedStatus int
+	}{
+		{
+			name:           "Warm resource is not accounted again",
+			policy:         scalertypes.WarmResourcesBudgetExceededPolicyReject,
+			resourceName:   "warm",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Reject beyond budget",
+			policy:         scalertypes.WarmResourcesBudgetExceededPolicyReject,
+			resourceName:   "cold",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Queue until budget frees up",
+			policy:         scalertypes.WarmResourcesBudgetExceededPolicyQueue,
+			resourceName:   "cold",
+			expectedStatus: http.StatusOK,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+			suite.functionStarter.warmResourcesBudget = scalertypes.WarmResourcesBudget{
+				MaxWarmResources: 1,
+				ExceededPolicy:   testCase.policy,
+			}
+			suite.functionStarter.warmResourcesBudgetPollInterval = 10 * time.Millisecond
+
+			suite.mocker.
+				On("GetResources").
+				Return(warmResources, nil).
+				Once()
+			suite.mocker.
+				On("GetResources").
+				Return(freedResources, nil)
+			suite.mocker.
+				On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				Return(nil)
+
+			ch := make(responseChannel)
+			suite.functionStarter.handleResourceStart(testCase.resourceName, ch)
+			r := <-ch
+			suite.Require().Equal(testCase.expectedStatus, r.Status)
+
+			if testCase.expectedStatus == http.StatusServiceUnavailable {
+				suite.Require().ErrorIs(r.Error, ErrWarmResourcesBudgetExceeded)
+				suite.mocker.AssertNotCalled(suite.T(), "SetScaleCtx", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func (suite *resourceStarterTest) TestDlxWarmResourcesBudgetRejectionIsNotCached() {
+	scaledToZeroEvent := scalertypes.ScaleToZeroCompletedScaleEvent
+	suite.functionStarter.warmResourcesBudget = scalertypes.WarmResourcesBudget{
+		MaxWarmResources: 1,
+		ExceededPolicy:   scalertypes.WarmResourcesBudgetExceededPolicyReject,
+	}
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{
+			{Name: "warm", Namespace: "default"},
+			{Name: "cold", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+		}, nil).
+		Once()
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{
+			{Name: "warm", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+			{Name: "cold", Namespace: "default", LastScaleEvent: &scaledToZeroEvent},
+		}, nil)
+	suite.mocker.
+		On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	ch := make(responseChannel)
+	suite.functionStarter.handleResourceStart("cold", ch)
+	r := <-ch
+	suite.Require().Equal(http.StatusServiceUnavailable, r.Status)
+
+	// once the budget frees up, the next request is evaluated against it again rather than served the rejection
+	ch = make(responseChannel)
+	suite.functionStarter.handleResourceStart("cold", ch)
+	r = <-ch
+	suite.Require().Equal(http.StatusOK, r.Status)
+}
+
+func (suite *resourceStarterTest) TestDlxWakeReplicas() {
+	for _, testCase := range []struct {
+		name                   string
+		resource               scalertypes.Resource
+		wakeRequestsPerReplica int
+		maxWakeReplicas        int
+		waitingRequests        int
+		expectedReplicas       []int
+	}{
+		{
+			name:             "Default wake replicas",
+			resource:         scalertypes.Resource{Name: "test"},
+			waitingRequests:  10,
+			expectedReplicas: []int{1},
+		},
+		{
+			name:             "Resource wake replicas",
+			resource:         scalertypes.Resource{Name: "test", WakeReplicas: 3},
+			waitingRequests:  10,
+			expectedReplicas: []int{3},
+		},
+		{
+			name:                   "Scale for waiting requests up to options max",
+			resource:               scalertypes.Resource{Name: "test"},
+			wakeRequestsPerReplica: 2,
+			maxWakeReplicas:        4,
+			waitingRequests:        10,
+			expectedReplicas:       []int{1, 4},
+		},
+		{
+			name:                   "Scale for waiting requests up to resource max",
+			resource:               scalertypes.Resource{Name: "test", WakeReplicas: 2, MaxWakeReplicas: 8},
+			wakeRequestsPerReplica: 2,
+			maxWakeReplicas:        4,
+			waitingRequests:        10,
+			expectedReplicas:       []int{2, 5},
+		},
+		{
+			name:                   "Not enough waiting requests",
+			resource:               scalertypes.Resource{Name: "test", WakeReplicas: 2, MaxWakeReplicas: 8},
+			wakeRequestsPerReplica: 5,
+			waitingRequests:        10,
+			expectedReplicas:       []int{2},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+			suite.functionStarter.wakeRequestsPerReplica = testCase.wakeRequestsPerReplica
+			suite.functionStarter.maxWakeReplicas = testCase.maxWakeReplicas
+
+			// hold the resource start until all the requests are waiting
+			allRequestsWaiting := make(chan struct{})
+			replicasChannel := make(chan int, 2)
+			suite.mocker.
+				On("GetResources").
+				Return([]scalertypes.Resource{testCase.resource}, nil)
+			suite.mocker.
+				On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					<-allRequestsWaiting
+					replicasChannel <- args.Int(2)
+				}).
+				Return(nil)
+
+			wg := sync.WaitGroup{}
+			for range testCase.waitingRequests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ch := make(responseChannel)
+					suite.functionStarter.handleResourceStart("test", ch)
+					r := <-ch
+					suite.Require().Equal(http.StatusOK, r.Status)
+				}()
+			}
+			suite.Require().Eventually(func() bool {
+				return suite.functionStarter.getResourceWaitingRequests("test") == int64(testCase.waitingRequests)
+			}, time.Second, time.Millisecond)
+			close(allRequestsWaiting)
+			wg.Wait()
+
+			for _, expectedReplicas := range testCase.expectedReplicas {
+				select {
+				case replicas := <-replicasChannel:
+					suite.Require().Equal(expectedReplicas, replicas)
+				case <-time.After(time.Second):
+					suite.Fail("Timed out waiting for scale", "expectedReplicas", expectedReplicas)
+				}
+			}
+			suite.Require().Empty(replicasChannel)
+			suite.Require().Zero(suite.functionStarter.getResourceWaitingRequests("test"))
+		})
+	}
+}
+
+func (suite *resourceStarterTest) TestDlxWaitingRequestsLimits() {
+	for _, testCase := range []struct {
+		name                          string
+		maxWaitingRequestsPerResource int64
+		maxWaitingRequests            int64
+		resourceNames                 []string
+		expectedRejectedStatus        int
+	}{
+		{
+			name:                          "Per resource limit",
+			maxWaitingRequestsPerResource: 2,
+			resourceNames:                 []string{"test1", "test2", "test1", "test2", "test1"},
+			expectedRejectedStatus:        http.StatusTooManyRequests,
+		},
+		{
+			name:                   "Global limit",
+			maxWaitingRequests:     4,
+			resourceNames:          []string{"test1", "test2", "test3", "test4", "test5"},
+			expectedRejectedStatus: http.StatusServiceUnavailable,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.SetupTest()
+			suite.functionStarter.maxWaitingRequestsPerResource = testCase.maxWaitingRequestsPerResource
+			suite.functionStarter.maxWaitingRequests = testCase.maxWaitingRequests
+
+			// hold the resources start until the last request is rejected
+			releaseResources := make(chan struct{})
+			suite.mocker.
+				On("GetResources").
+				Return([]scalertypes.Resource{}, nil)
+			suite.mocker.
+				On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					<-releaseResources
+				}).
+				Return(nil)
+
+			lastResourceName := testCase.resourceNames[len(testCase.resourceNames)-1]
+			responseChannels := make([]responseChannel, len(testCase.resourceNames)-1)
+			for idx, resourceName := range testCase.resourceNames[:len(testCase.resourceNames)-1] {
+				responseChannels[idx] = make(responseChannel, 1)
+				go suite.functionStarter.handleResourceStart(resourceName, responseChannels[idx])
+			}
+			suite.Require().Eventually(func() bool {
+				totalWaitingRequests, _ := suite.functionStarter.GetWaitingRequests()
+				return totalWaitingRequests == len(responseChannels)
+			}, time.Second, time.Millisecond)
+
+			rejectedChannel := make(responseChannel, 1)
+			suite.functionStarter.handleResourceStart(lastResourceName, rejectedChannel)
+			rejected := <-rejectedChannel
+			suite.Require().Equal(testCase.expectedRejectedStatus, rejected.Status)
+			suite.Require().ErrorIs(rejected.Error, ErrTooManyWaitingRequests)
+			suite.Require().GreaterOrEqual(rejected.RetryAfter, time.Second)
+
+			close(releaseResources)
+			for _, ch := range responseChannels {
+				r := <-ch
+				suite.Require().Equal(http.StatusOK, r.Status)
+			}
+
+			suite.Require().Eventually(func() bool {
+				totalWaitingRequests, waitingRequestsPerResource := suite.functionStarter.GetWaitingRequests()
+				return totalWaitingRequests == 0 && len(waitingRequestsPerResource) == 0
+			}, time.Second, time.Millisecond)
+
+			// the counters of resources no request waits for are not kept
+			suite.functionStarter.waitingRequestsMap.Range(func(key, value interface{}) bool {
+				suite.Failf("Waiting requests counter was kept", "resourceName: %s", key)
+				return true
+			})
+		})
+	}
+}
+
+func (suite *resourceStarterTest) TestDlxExpectedReadinessDuration() {
+	suite.functionStarter.resourceReadinessTimeout = time.Minute
+
+	// unknown resource
+	suite.Require().Equal(time.Second, suite.functionStarter.getExpectedReadinessDuration("test"))
+
+	// first wake-up, expected to take up to the readiness timeout
+	suite.functionStarter.setWakeStarted("test")
+	suite.Require().InDelta(time.Minute, suite.functionStarter.getExpectedReadinessDuration("test"), float64(time.Second))
+
+	// woken up
+	suite.functionStarter.setWakeEnded("test", nil)
+	suite.Require().Equal(time.Second, suite.functionStarter.getExpectedReadinessDuration("test"))
+
+	// next wake-up is expected to take as long as the last one
+	suite.functionStarter.wakeStatusesMap.Store("test", resourceWakeStatus{
+		startTime:        time.Now(),
+		lastWakeDuration: 30 * time.Second,
+	})
+	suite.Require().InDelta(30*time.Second, suite.functionStarter.getExpectedReadinessDuration("test"), float64(time.Second))
+}
+
+func (suite *resourceStarterTest) TestDlxWakeStatusesEviction() {
+	suite.functionStarter.maxWakeStatuses = 2
+
+	suite.functionStarter.setWakeStarted("first")
+	suite.functionStarter.setWakeEnded("first", nil)
+	suite.functionStarter.setWakeStarted("second")
+	suite.functionStarter.setWakeEnded("second", nil)
+
+	// waking a known resource again keeps all the statuses
+	suite.functionStarter.setWakeStarted("first")
+	suite.functionStarter.setWakeEnded("first", nil)
+	_, found := suite.functionStarter.getWakeStatus("second")
+	suite.Require().True(found)
+
+	// a new resource evicts the status of the wake-up that ended the longest ago
+	suite.functionStarter.setWakeStarted("third")
+	_, found = suite.functionStarter.getWakeStatus("second")
+	suite.Require().False(found)
+	for _, resourceName := range []string{"first", "third"} {
+		_, found = suite.functionStarter.getWakeStatus(resourceName)
+		suite.Require().True(found)
+	}
+
+	// wake-ups in progress are not evicted
+	suite.functionStarter.setWakeStarted("fourth")
+	_, found = suite.functionStarter.getWakeStatus("third")
+	suite.Require().True(found)
+	suite.Require().Equal(2, suite.functionStarter.wakeStatusesCount)
+}
+
+func (suite *resourceStarterTest) TestDlxReadinessCache() {
+	suite.functionStarter.readinessCache = NewReadinessCache(suite.logger, time.Minute)
+	suite.mocker.
+		On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{}, nil)
+	suite.mocker.
+		On("ResolveServiceName", scalertypes.Resource{Name: "test"}).
+		Return("test-service", nil)
+
+	startResource := func() {
+		ch := make(responseChannel, 1)
+		suite.functionStarter.handleResourceStart("test", ch)
+		suite.Require().Equal(http.StatusOK, (<-ch).Status)
+
+		// drop the sink, as if it expired, so that the next request does not reuse its result
+		suite.functionStarter.deleteResourceSink("test")
+	}
+
+	// first request starts the resource and caches it as ready
+	startResource()
+	suite.Require().True(suite.functionStarter.readinessCache.IsReady("test"))
+	suite.mocker.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+
+	// a warm resource is not started again
+	startResource()
+	suite.mocker.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+
+	// once the service endpoints change, the resource is started again
+	suite.functionStarter.readinessCache.InvalidateService("test-service")
+	suite.Require().False(suite.functionStarter.readinessCache.IsReady("test"))
+	startResource()
+	suite.mocker.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 2)
+
+	// as it is once the readiness expires
+	suite.functionStarter.readinessCache.readyResources["test"] = readinessCacheEntry{
+		serviceName: "test-service",
+		readyTime:   time.Now().Add(-2 * time.Minute),
+	}
+	suite.Require().False(suite.functionStarter.readinessCache.IsReady("test"))
+	startResource()
+	suite.mocker.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 3)
+
+	// other services do not affect the resource readiness
+	suite.functionStarter.readinessCache.InvalidateService("other-service")
+	suite.Require().True(suite.functionStarter.readinessCache.IsReady("test"))
+	suite.functionStarter.readinessCache.Invalidate("test")
+	suite.Require().False(suite.functionStarter.readinessCache.IsReady("test"))
+}
+
+func (suite *resourceStarterTest) TestDlxReadinessProbe() {
+	var probes atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// the service is not ready for the first probes
+		if probes.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	testServerURL, err := url.Parse(testServer.URL)
+	suite.Require().NoError(err)
+	testServerPort, err := strconv.Atoi(testServerURL.Port())
+	suite.Require().NoError(err)
+
+	suite.mocker.
+		On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	suite.mocker.
+		On("GetResources").
+		Return([]scalertypes.Resource{}, nil)
+	suite.mocker.
+		On("ResolveServiceName", mock.Anything).
+		Return(testServerURL.Hostname(), nil)
+
+	for _, testCase := range []struct {
+		name           string
+		probeOptions   scalertypes.ReadinessProbeOptions
+		expectedStatus int
+		expectedProbes int32
+	}{
+		{
+			name: "http probe succeeds once the service is ready",
+			probeOptions: scalertypes.ReadinessProbeOptions{
+				Type:     scalertypes.ReadinessProbeTypeHTTP,
+				Path:     "/healthz",
+				Port:     testServerPort,
+				Interval: scalertypes.Duration{Duration: 10 * time.Millisecond},
+			},
+			expectedStatus: http.StatusOK,
+			expectedProbes: 3,
+		}, {
+			name: "tcp probe succeeds",
+			probeOptions: scalertypes.ReadinessProbeOptions{
+				Type: scalertypes.ReadinessProbeTypeTCP,
+				Port: testServerPort,
+			},
+			expectedStatus: http.StatusOK,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			probes.Store(0)
+			suite.functionStarter.readinessProber, err = newReadinessProber(suite.logger, testCase.probeOptions, 0)
+			suite.Require().NoError(err)
+
+			ch := make(responseChannel, 1)
+			suite.functionStarter.handleResourceStart(testCase.name, ch)
+			suite.Require().Equal(testCase.expectedStatus, (<-ch).Status)
+			suite.Require().Equal(testCase.expectedProbes, probes.Load())
+		})
+	}
+
+	// a service that never accepts connections fails the resource start
+	testServer.Close()
+	suite.functionStarter.readinessProber, err = newReadinessProber(suite.logger, scalertypes.ReadinessProbeOptions{
+		Type:     scalertypes.ReadinessProbeTypeTCP,
+		Port:     testServerPort,
+		Interval: scalertypes.Duration{Duration: 10 * time.Millisecond},
+	}, 0)
+	suite.Require().NoError(err)
+
+	ch := make(responseChannel, 1)
+	suite.functionStarter.handleResourceStart("never-ready", ch)
+	suite.Require().Error((<-ch).Error)
+
+	// unknown probe types are rejected
+	_, err = newReadinessProber(suite.logger, scalertypes.ReadinessProbeOptions{Type: "grpc"}, 0)
+	suite.Require().Error(err)
+}
+
+func (suite *resourceStarterTest) resourcesNamed(resourceName string) interface{} {
+	return mock.MatchedBy(func(resources []scalertypes.Resource) bool {
+		return len(resources) == 1 && resources[0].Name == resourceName
+	})
+}
+
 func TestResourceStarter(t *testing.T) {
 	suite.Run(t, new(resourceStarterTest))
 }
@@ -0,0 +1,186 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const (
+	defaultProxyRetryBackoff    = 100 * time.Millisecond
+	defaultProxyRetryMaxBackoff = time.Second
+)
+
+// retryDeadlineContextKey holds the time until which a proxied request may be retried
+type retryDeadlineContextKey struct{}
+
+// warmUpRetryTransport retries requests that fail to reach a resource that was just woken up, as its service may
+// accept connections only a while after the resource is reported ready. only requests carrying a retry deadline in
+// their context are retried. gRPC requests are never retried, as their streams cannot be buffered - gRPC clients
+// retry the UNAVAILABLE status they fail with instead
+type warmUpRetryTransport struct {
+	logger      logger.Logger
+	transport   http.RoundTripper
+	maxBodySize int64
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+func newWarmUpRetryTransport(parentLogger logger.Logger,
+	transport http.RoundTripper,
+	options scalertypes.ProxyRetryOptions) *warmUpRetryTransport {
+	rt := &warmUpRetryTransport{
+		logger:      parentLogger.GetChild("retry-transport"),
+		transport:   transport,
+		maxBodySize: options.MaxBodySize,
+		backoff:     options.Backoff.Duration,
+		maxBackoff:  options.MaxBackoff.Duration,
+	}
+	if rt.backoff == 0 {
+		rt.backoff = defaultProxyRetryBackoff
+	}
+	if rt.maxBackoff == 0 {
+		rt.maxBackoff = defaultProxyRetryMaxBackoff
+	}
+
+	return rt
+}
+
+func withRetryDeadline(req *http.Request, deadline time.Time) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), retryDeadlineContextKey{}, deadline))
+}
+
+func (rt *warmUpRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, found := req.Context().Value(retryDeadlineContextKey{}).(time.Time)
+	if !found || time.Now().After(deadline) || isGRPCRequest(req) {
+		return rt.transport.RoundTrip(req)
+	}
+
+	getBody, err := rt.bufferBody(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to buffer request body")
+	}
+
+	// the body is too large to replay, send it once
+	if getBody == nil {
+		return rt.transport.RoundTrip(req)
+	}
+
+	backoff := rt.backoff
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if attemptReq.Body, err = getBody(); err != nil {
+			return nil, errors.Wrap(err, "Failed to get request body")
+		}
+
+		response, err := rt.transport.RoundTrip(attemptReq)
+		if err == nil {
+			return response, nil
+		}
+
+		if !rt.isRetryable(req, err) || time.Now().Add(backoff).After(deadline) {
+			return nil, err
+		}
+
+		rt.logger.DebugWithCtx(req.Context(), "Retrying request to warming up resource",
+			"url", req.URL.String(),
+			"attempt", attempt,
+			"backoff", backoff,
+			"err", err.Error())
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, rt.maxBackoff)
+	}
+}
+
+// bufferBody reads the request body into memory, and returns a function that returns a fresh copy of it. when the
+// body exceeds the maximal body size, the request body is restored from what was read and nil is returned
+func (rt *warmUpRetryTransport) bufferBody(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}, nil
+	}
+
+	if req.ContentLength > rt.maxBodySize {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, rt.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > rt.maxBodySize {
+		req.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(body), req.Body),
+			Closer: req.Body,
+		}
+		return nil, nil
+	}
+
+	req.Body.Close() // nolint: errcheck
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}, nil
+}
+
+// isRetryable returns true if the request may be sent again after the error. a refused connection never reached the
+// service, so any request may be retried, while other connection errors are retried only for idempotent requests
+func (rt *warmUpRetryTransport) isRetryable(req *http.Request, err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	if !errors.Is(err, syscall.ECONNRESET) && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	// same as the standard library transport, a request with an idempotency key is considered idempotent
+	_, hasIdempotencyKey := req.Header["Idempotency-Key"]
+	_, hasXIdempotencyKey := req.Header["X-Idempotency-Key"]
+	return hasIdempotencyKey || hasXIdempotencyKey
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const defaultTCPProxyDialTimeout = 30 * time.Second
+
+// TCPProxy wakes resources that serve raw TCP, such as databases and message brokers. each listener maps to a single
+// resource - a connection accepted on it is held until the resource is ready, and is then spliced to the resource
+// service
+type TCPProxy struct {
+	logger          logger.Logger
+	resourceStarter *ResourceStarter
+	resourceScaler  scalertypes.ResourceScaler
+	listeners       []scalertypes.TCPProxyListener
+	dialer          *net.Dialer
+
+	lock         sync.Mutex
+	netListeners []net.Listener
+	connections  map[net.Conn]struct{}
+	waitGroup    sync.WaitGroup
+}
+
+func NewTCPProxy(parentLogger logger.Logger,
+	resourceStarter *ResourceStarter,
+	resourceScaler scalertypes.ResourceScaler,
+	listeners []scalertypes.TCPProxyListener,
+	dialTimeout time.Duration) (*TCPProxy, error) {
+	for _, listener := range listeners {
+		if listener.ResourceName == "" {
+			return nil, errors.Errorf("TCP proxy listener %s has no resource", listener.ListenAddress)
+		}
+		if listener.TargetPort <= 0 {
+			return nil, errors.Errorf("TCP proxy listener %s has an invalid target port: %d",
+				listener.ListenAddress,
+				listener.TargetPort)
+		}
+	}
+
+	if dialTimeout == 0 {
+		dialTimeout = defaultTCPProxyDialTimeout
+	}
+
+	return &TCPProxy{
+		logger:          parentLogger.GetChild("tcp-proxy"),
+		resourceStarter: resourceStarter,
+		resourceScaler:  resourceScaler,
+		listeners:       listeners,
+		dialer: &net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		},
+		connections: map[net.Conn]struct{}{},
+	}, nil
+}
+
+func (p *TCPProxy) Start() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, listener := range p.listeners {
+		netListener, err := net.Listen("tcp", listener.ListenAddress)
+		if err != nil {
+			p.closeListeners()
+			return errors.Wrapf(err, "Failed to listen on %s", listener.ListenAddress)
+		}
+
+		p.logger.DebugWith("Listening",
+			"address", netListener.Addr().String(),
+			"resourceName", listener.ResourceName,
+			"targetPort", listener.TargetPort)
+		p.netListeners = append(p.netListeners, netListener)
+
+		p.waitGroup.Add(1)
+		go p.acceptConnections(netListener, listener)
+	}
+
+	return nil
+}
+
+// Stop stops accepting connections, and waits for the open connections to be closed until the context is done, after
+// which they are closed
+func (p *TCPProxy) Stop(ctx context.Context) error {
+	p.lock.Lock()
+	p.closeListeners()
+	p.lock.Unlock()
+
+	doneChan := make(chan struct{})
+	go func() {
+		p.waitGroup.Wait()
+		close(doneChan)
+	}()
+
+	select {
+	case <-doneChan:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.lock.Lock()
+	openConnections := len(p.connections)
+	for connection := range p.connections {
+		connection.Close() // nolint: errcheck
+	}
+	p.lock.Unlock()
+
+	return errors.Wrapf(ctx.Err(), "Closed %d open connections", openConnections)
+}
+
+func (p *TCPProxy) closeListeners() {
+	for _, netListener := range p.netListeners {
+		netListener.Close() // nolint: errcheck
+	}
+	p.netListeners = nil
+}
+
+func (p *TCPProxy) acceptConnections(netListener net.Listener, listener scalertypes.TCPProxyListener) {
+	defer p.waitGroup.Done()
+
+	for {
+		connection, err := netListener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				p.logger.WarnWith("Failed to accept connection",
+					"address", listener.ListenAddress,
+					"err", err.Error())
+			}
+			return
+		}
+
+		p.trackConnection(connection)
+		p.waitGroup.Add(1)
+		go p.handleConnection(connection, listener)
+	}
+}
+
+func (p *TCPProxy) handleConnection(connection net.Conn, listener scalertypes.TCPProxyListener) {
+	defer p.waitGroup.Done()
+	defer p.untrackConnection(connection)
+	defer connection.Close() // nolint: errcheck
+
+	// a raw connection has no way to report a failure, so it is closed
+	responseChannel := make(responseChannel, 1)
+	go p.resourceStarter.handleResourceStart(listener.ResourceName, responseChannel)
+	if statusResult := <-responseChannel; statusResult.Error != nil {
+		p.logger.WarnWith("Failed to start resource, closing connection",
+			"resourceName", listener.ResourceName,
+			"remoteAddress", connection.RemoteAddr().String(),
+			"err", errors.GetErrorStackString(statusResult.Error, 10))
+		return
+	}
+
+	serviceName, err := p.resourceScaler.ResolveServiceName(scalertypes.Resource{Name: listener.ResourceName})
+	if err != nil {
+		p.logger.WarnWith("Failed to resolve service name, closing connection",
+			"resourceName", listener.ResourceName,
+			"err", errors.GetErrorStackString(err, 10))
+		return
+	}
+
+	targetAddress := net.JoinHostPort(serviceName, strconv.Itoa(listener.TargetPort))
+	targetConnection, err := p.dialer.Dial("tcp", targetAddress)
+	if err != nil {
+		p.logger.WarnWith("Failed to connect to resource, closing connection",
+			"resourceName", listener.ResourceName,
+			"targetAddress", targetAddress,
+			"err", err.Error())
+
+		// the resource may have been scaled to zero since it was cached as ready
+		p.resourceStarter.invalidateResourceReadiness(listener.ResourceName)
+		return
+	}
+	p.trackConnection(targetConnection)
+	defer p.untrackConnection(targetConnection)
+	defer targetConnection.Close() // nolint: errcheck
+
+	p.splice(connection, targetConnection)
+}
+
+// splice copies bytes both ways until both sides are done. when one side is done sending, the other side is notified
+// with a half close, so that protocols that rely on it keep working
+func (p *TCPProxy) splice(connection net.Conn, targetConnection net.Conn) {
+	copyDoneChan := make(chan struct{}, 2)
+	copyStream := func(destination net.Conn, source net.Conn) {
+		io.Copy(destination, source) // nolint: errcheck
+		if tcpConnection, ok := destination.(*net.TCPConn); ok {
+			tcpConnection.CloseWrite() // nolint: errcheck
+		} else {
+			destination.Close() // nolint: errcheck
+		}
+		copyDoneChan <- struct{}{}
+	}
+
+	go copyStream(targetConnection, connection)
+	go copyStream(connection, targetConnection)
+	<-copyDoneChan
+	<-copyDoneChan
+}
+
+func (p *TCPProxy) trackConnection(connection net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.connections[connection] = struct{}{}
+}
+
+func (p *TCPProxy) untrackConnection(connection net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.connections, connection)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	resourcescalerMock "github.com/v3io/scaler/pkg/resourcescaler/mock"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TCPProxyTestSuite struct {
+	suite.Suite
+	logger         logger.Logger
+	scaler         *resourcescalerMock.ResourceScaler
+	echoListener   net.Listener
+	echoTargetPort int
+}
+
+func (suite *TCPProxyTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *TCPProxyTestSuite) SetupTest() {
+	suite.scaler = &resourcescalerMock.ResourceScaler{}
+
+	// a raw TCP target that echoes everything it receives until the client is done sending
+	echoListener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	suite.echoListener = echoListener
+	suite.echoTargetPort = echoListener.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			connection, err := echoListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer connection.Close()        // nolint: errcheck
+				io.Copy(connection, connection) // nolint: errcheck
+			}()
+		}
+	}()
+}
+
+func (suite *TCPProxyTestSuite) TearDownTest() {
+	suite.echoListener.Close() // nolint: errcheck
+}
+
+func (suite *TCPProxyTestSuite) TestProxyConnection() {
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("127.0.0.1", nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	tcpProxy := suite.createAndStartTCPProxy()
+	defer tcpProxy.Stop(context.Background()) // nolint: errcheck
+
+	// every connection is held until the resource is woken, and then spliced to it
+	for i := 0; i < 3; i++ {
+		connection, err := net.Dial("tcp", tcpProxy.netListeners[0].Addr().String())
+		suite.Require().NoError(err)
+
+		message := "message-" + strconv.Itoa(i)
+		_, err = connection.Write([]byte(message))
+		suite.Require().NoError(err)
+
+		// half closing the connection is propagated to the target, which then ends the connection
+		suite.Require().NoError(connection.(*net.TCPConn).CloseWrite())
+		echoed, err := io.ReadAll(connection)
+		suite.Require().NoError(err)
+		suite.Require().Equal(message, string(echoed))
+		suite.Require().NoError(connection.Close())
+	}
+
+	// the resource is woken once for all the connections
+	suite.scaler.AssertNumberOfCalls(suite.T(), "SetScaleCtx", 1)
+}
+
+func (suite *TCPProxyTestSuite) TestWakeFailureClosesConnection() {
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("127.0.0.1", nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("failed to scale"))
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	tcpProxy := suite.createAndStartTCPProxy()
+	defer tcpProxy.Stop(context.Background()) // nolint: errcheck
+
+	connection, err := net.Dial("tcp", tcpProxy.netListeners[0].Addr().String())
+	suite.Require().NoError(err)
+	defer connection.Close() // nolint: errcheck
+
+	suite.Require().NoError(connection.SetReadDeadline(time.Now().Add(3 * time.Second)))
+	read, err := io.ReadAll(connection)
+	suite.Require().NoError(err)
+	suite.Require().Empty(read)
+}
+
+func (suite *TCPProxyTestSuite) TestStopClosesOpenConnections() {
+	suite.scaler.On("ResolveServiceName", mock.Anything).Return("127.0.0.1", nil)
+	suite.scaler.On("SetScaleCtx", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.scaler.On("GetResources").Return([]scalertypes.Resource{}, nil)
+
+	tcpProxy := suite.createAndStartTCPProxy()
+	listenAddress := tcpProxy.netListeners[0].Addr().String()
+
+	connection, err := net.Dial("tcp", listenAddress)
+	suite.Require().NoError(err)
+	defer connection.Close() // nolint: errcheck
+
+	// make sure the connection is spliced before stopping
+	_, err = connection.Write([]byte("ping"))
+	suite.Require().NoError(err)
+	echoed := make([]byte, 4)
+	_, err = io.ReadFull(connection, echoed)
+	suite.Require().NoError(err)
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	suite.Require().Error(tcpProxy.Stop(stopCtx))
+
+	// the open connection was closed, and no new connections are accepted
+	suite.Require().NoError(connection.SetReadDeadline(time.Now().Add(3 * time.Second)))
+	_, err = io.ReadAll(connection)
+	suite.Require().NoError(err)
+	_, err = net.Dial("tcp", listenAddress)
+	suite.Require().Error(err)
+}
+
+func (suite *TCPProxyTestSuite) TestNewTCPProxyValidation() {
+	for _, testCase := range []struct {
+		name     string
+		listener scalertypes.TCPProxyListener
+	}{
+		{
+			name:     "Missing resource",
+			listener: scalertypes.TCPProxyListener{ListenAddress: ":5432", TargetPort: 5432},
+		}, {
+			name:     "Missing target port",
+			listener: scalertypes.TCPProxyListener{ListenAddress: ":5432", ResourceName: "postgres"},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := NewTCPProxy(suite.logger,
+				nil,
+				suite.scaler,
+				[]scalertypes.TCPProxyListener{testCase.listener},
+				0)
+			suite.Require().Error(err)
+		})
+	}
+}
+
+// --- TCPProxyTestSuite suite methods ---
+
+func (suite *TCPProxyTestSuite) createAndStartTCPProxy() *TCPProxy {
+	resourceStarter := &ResourceStarter{
+		logger:                   suite.logger,
+		scaler:                   suite.scaler,
+		resourceReadinessTimeout: 3 * time.Second,
+	}
+
+	tcpProxy, err := NewTCPProxy(suite.logger,
+		resourceStarter,
+		suite.scaler,
+		[]scalertypes.TCPProxyListener{
+			{
+				ListenAddress: "127.0.0.1:0",
+				ResourceName:  "test-resource",
+				TargetPort:    suite.echoTargetPort,
+			},
+		},
+		time.Second)
+	suite.Require().NoError(err)
+	suite.Require().NoError(tcpProxy.Start())
+	return tcpProxy
+}
+
+func TestTCPProxyTestSuite(t *testing.T) {
+	suite.Run(t, new(TCPProxyTestSuite))
+}
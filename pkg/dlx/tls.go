@@ -0,0 +1,175 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/v3io/scaler/pkg/kube"
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+const defaultTLSReloadInterval = 10 * time.Second
+
+// certificateStore selects the certificate for a TLS handshake by its server name (SNI) - the certificate of the
+// ingress secret for that host if there is one, and otherwise the certificate loaded from files. the files are
+// polled for changes, and reloaded when they are modified
+type certificateStore struct {
+	logger           logger.Logger
+	certFile         string
+	keyFile          string
+	reloadInterval   time.Duration
+	tlsSecretWatcher *kube.TLSSecretWatcher
+	stopChan         chan struct{}
+	stopOnce         sync.Once
+
+	lock             sync.RWMutex
+	fileCertificate  *tls.Certificate
+	fileModification time.Time
+}
+
+func newCertificateStore(parentLogger logger.Logger,
+	options scalertypes.TLSOptions,
+	tlsSecretWatcher *kube.TLSSecretWatcher) (*certificateStore, error) {
+	cs := &certificateStore{
+		logger:           parentLogger.GetChild("certificate-store"),
+		certFile:         options.CertFile,
+		keyFile:          options.KeyFile,
+		reloadInterval:   options.ReloadInterval.Duration,
+		tlsSecretWatcher: tlsSecretWatcher,
+		stopChan:         make(chan struct{}),
+	}
+	if cs.reloadInterval == 0 {
+		cs.reloadInterval = defaultTLSReloadInterval
+	}
+
+	if cs.certFile != "" {
+		if err := cs.reloadFileCertificate(); err != nil {
+			return nil, errors.Wrap(err, "Failed to load certificate")
+		}
+	}
+
+	return cs, nil
+}
+
+func (cs *certificateStore) start() error {
+	if cs.tlsSecretWatcher != nil {
+		if err := cs.tlsSecretWatcher.Start(); err != nil {
+			return errors.Wrap(err, "Failed to start TLS secret watcher")
+		}
+	}
+
+	if cs.certFile != "" {
+		go cs.watchFileCertificate()
+	}
+
+	return nil
+}
+
+func (cs *certificateStore) stop() {
+	cs.stopOnce.Do(func() {
+		close(cs.stopChan)
+		if cs.tlsSecretWatcher != nil {
+			cs.tlsSecretWatcher.Stop()
+		}
+	})
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (cs *certificateStore) GetCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cs.tlsSecretWatcher != nil && clientHello.ServerName != "" {
+		if certificate, found := cs.tlsSecretWatcher.GetCertificate(clientHello.ServerName); found {
+			return certificate, nil
+		}
+	}
+
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+
+	if cs.fileCertificate == nil {
+		return nil, errors.Errorf("No certificate for server name: %s", clientHello.ServerName)
+	}
+	return cs.fileCertificate, nil
+}
+
+func (cs *certificateStore) watchFileCertificate() {
+	ticker := time.NewTicker(cs.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cs.stopChan:
+			return
+		case <-ticker.C:
+
+			// a failed reload, e.g. while the files are being replaced, keeps serving the previous certificate
+			if err := cs.reloadFileCertificate(); err != nil {
+				cs.logger.WarnWith("Failed to reload certificate",
+					"certFile", cs.certFile,
+					"keyFile", cs.keyFile,
+					"err", errors.GetErrorStackString(err, 10))
+			}
+		}
+	}
+}
+
+// reloadFileCertificate loads the certificate from the files if any of them was modified since it was last loaded
+func (cs *certificateStore) reloadFileCertificate() error {
+	var lastModification time.Time
+	for _, path := range []string{cs.certFile, cs.keyFile} {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to stat %s", path)
+		}
+		if fileInfo.ModTime().After(lastModification) {
+			lastModification = fileInfo.ModTime()
+		}
+	}
+
+	cs.lock.RLock()
+	modified := !lastModification.Equal(cs.fileModification)
+	cs.lock.RUnlock()
+	if !modified {
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(cs.certFile, cs.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load key pair")
+	}
+
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	cs.fileCertificate = &certificate
+	cs.fileModification = lastModification
+	cs.logger.InfoWith("Loaded certificate",
+		"certFile", cs.certFile,
+		"modificationTime", lastModification)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type CertificateStoreTestSuite struct {
+	suite.Suite
+	logger   logger.Logger
+	certFile string
+	keyFile  string
+}
+
+func (suite *CertificateStoreTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *CertificateStoreTestSuite) SetupTest() {
+	tempDir := suite.T().TempDir()
+	suite.certFile = filepath.Join(tempDir, "tls.crt")
+	suite.keyFile = filepath.Join(tempDir, "tls.key")
+}
+
+func (suite *CertificateStoreTestSuite) TestReloadFileCertificate() {
+	suite.writeCertificateFiles(time.Now().Add(-time.Minute))
+
+	certificates, err := newCertificateStore(suite.logger, scalertypes.TLSOptions{
+		CertFile:       suite.certFile,
+		KeyFile:        suite.keyFile,
+		ReloadInterval: scalertypes.Duration{Duration: 10 * time.Millisecond},
+	}, nil)
+	suite.Require().NoError(err)
+	suite.Require().NoError(certificates.start())
+	defer certificates.stop()
+
+	certificate, err := certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	suite.Require().NoError(err)
+	initialCertificate := certificate.Certificate[0]
+
+	// invalid files are not loaded, and the previous certificate is still served
+	suite.Require().NoError(os.WriteFile(suite.certFile, []byte("invalid"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	certificate, err = certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(initialCertificate, certificate.Certificate[0])
+
+	// replaced files are served without a restart
+	suite.writeCertificateFiles(time.Now().Add(time.Minute))
+	suite.Require().Eventually(func() bool {
+		certificate, err := certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+		return err == nil && string(certificate.Certificate[0]) != string(initialCertificate)
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func (suite *CertificateStoreTestSuite) TestInvalidFiles() {
+	_, err := newCertificateStore(suite.logger, scalertypes.TLSOptions{
+		CertFile: suite.certFile,
+		KeyFile:  suite.keyFile,
+	}, nil)
+	suite.Require().Error(err)
+}
+
+// --- CertificateStoreTestSuite suite methods ---
+
+func (suite *CertificateStoreTestSuite) writeCertificateFiles(modificationTime time.Time) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	suite.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	suite.Require().NoError(err)
+
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	suite.Require().NoError(err)
+
+	for path, block := range map[string]*pem.Block{
+		suite.certFile: {Type: "CERTIFICATE", Bytes: certificateDER},
+		suite.keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		suite.Require().NoError(os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+		suite.Require().NoError(os.Chtimes(path, modificationTime, modificationTime))
+	}
+}
+
+func TestCertificateStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(CertificateStoreTestSuite))
+}
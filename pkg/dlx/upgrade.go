@@ -0,0 +1,153 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package dlx
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/nuclio/errors"
+)
+
+// isUpgradeRequest returns true if the request asks to switch protocols, e.g. to a WebSocket
+func isUpgradeRequest(req *http.Request) bool {
+	if req.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, connectionHeader := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(connectionHeader, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeTracker tracks the upgraded connections proxied by the DLX. once hijacked, a connection is no longer tracked
+// by the http server, so shutting the server down neither waits for it nor closes it
+type upgradeTracker struct {
+	lock        sync.Mutex
+	connections int
+
+	// closed whenever the last open connection is closed
+	idleChan chan struct{}
+}
+
+func newUpgradeTracker() *upgradeTracker {
+	return &upgradeTracker{}
+}
+
+func (t *upgradeTracker) add() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.connections == 0 {
+		t.idleChan = make(chan struct{})
+	}
+	t.connections++
+}
+
+func (t *upgradeTracker) done() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.connections--
+	if t.connections == 0 {
+		close(t.idleChan)
+	}
+}
+
+func (t *upgradeTracker) count() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.connections
+}
+
+// wait blocks until all the upgraded connections are closed or the context is done
+func (t *upgradeTracker) wait(ctx context.Context) error {
+	t.lock.Lock()
+	if t.connections == 0 {
+		t.lock.Unlock()
+		return nil
+	}
+	idleChan := t.idleChan
+	t.lock.Unlock()
+
+	select {
+	case <-idleChan:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "%d upgraded connections are still open", t.count())
+	}
+}
+
+// upgradeResponseWriter is handed to the reverse proxy for upgrade requests, so that the connection it hijacks once
+// the target switches protocols is tracked until it is closed. the http server clears its request timeouts from
+// hijacked connections, so they do not cut long-lived connections
+type upgradeResponseWriter struct {
+	http.ResponseWriter
+	tracker *upgradeTracker
+}
+
+func (w *upgradeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, bufferedReadWriter, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.tracker.add()
+	return &trackedConn{Conn: conn, tracker: w.tracker}, bufferedReadWriter, nil
+}
+
+func (w *upgradeResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type trackedConn struct {
+	net.Conn
+	tracker   *upgradeTracker
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.tracker.done)
+	return err
+}
+
+// newH2CTrackingHandler hands the requests upgrading to cleartext HTTP/2 - with prior knowledge or through an upgrade
+// header - to the h2c handler with an upgradeResponseWriter, so that the connections it hijacks are tracked as well
+func newH2CTrackingHandler(h2cHandler http.Handler, tracker *upgradeTracker) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		isPriorKnowledge := req.Method == "PRI" && req.URL.Path == "*" && req.Proto == "HTTP/2.0"
+		if isPriorKnowledge || (isUpgradeRequest(req) && strings.EqualFold(req.Header.Get("Upgrade"), "h2c")) {
+			res = &upgradeResponseWriter{ResponseWriter: res, tracker: tracker}
+		}
+		h2cHandler.ServeHTTP(res, req)
+	})
+}
@@ -40,9 +40,13 @@ func NewIngressCache(logger logger.Logger) *IngressCache {
 }
 
 func (ic *IngressCache) Set(host, path string, targets []string) error {
+	return ic.SetWeighted(host, path, targets, nil)
+}
+
+func (ic *IngressCache) SetWeighted(host, path string, targets []string, weights []int) error {
 	urlTree, exists := ic.syncMap.LoadOrStore(host, NewSafeTrie())
 
-	ingressHostsTree, ok := urlTree.(IngressHostsTree)
+	ingressHostsTree, ok := urlTree.(WeightedIngressHostsTree)
 	if !ok {
 		if !exists {
 			ic.syncMap.Delete(host)
@@ -50,7 +54,7 @@ func (ic *IngressCache) Set(host, path string, targets []string) error {
 		return errors.Errorf("cache set failed: invalid path tree value: got: %t", urlTree)
 	}
 
-	if err := ingressHostsTree.Set(path, targets); err != nil {
+	if err := ingressHostsTree.SetWeighted(path, targets, weights); err != nil {
 		if !exists {
 			ic.syncMap.Delete(host)
 		}
@@ -88,14 +92,19 @@ func (ic *IngressCache) Delete(host, path string, targets []string) error {
 }
 
 func (ic *IngressCache) Get(host, path string) ([]string, error) {
+	targets, _, err := ic.GetWeighted(host, path)
+	return targets, err
+}
+
+func (ic *IngressCache) GetWeighted(host, path string) ([]string, []int, error) {
 	urlTree, exists := ic.syncMap.Load(host)
 	if !exists {
-		return nil, errors.New("cache get failed: host does not exist")
+		return nil, nil, errors.New("cache get failed: host does not exist")
 	}
 
 	ingressHostsTree, ok := urlTree.(IngressHostsTree)
 	if !ok {
-		return nil, errors.Errorf("cache get failed: invalid path tree value: got: %t", urlTree)
+		return nil, nil, errors.Errorf("cache get failed: invalid path tree value: got: %t", urlTree)
 	}
 
 	result, err := ingressHostsTree.Get(path)
@@ -104,9 +113,9 @@ func (ic *IngressCache) Get(host, path string) ([]string, error) {
 		// Needed because the trie can’t resolve prefixes when "/" is both delimiter and root path.
 		result, err = ingressHostsTree.Get("/")
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to get the targets from the ingress host tree")
+			return nil, nil, errors.Wrap(err, "failed to get the targets from the ingress host tree")
 		}
 	}
 
-	return result.ToSliceString(), nil
+	return result.ToSliceString(), GetTargetWeights(result), nil
 }
@@ -42,11 +42,16 @@ func NewSafeTrie() *SafeTrie {
 
 // Set adds targets for a given path. If the path does not exist, it creates it
 func (st *SafeTrie) Set(path string, targets []string) error {
+	return st.SetWeighted(path, targets, nil)
+}
+
+// SetWeighted adds targets with their routing weights for a given path. If the path does not exist, it creates it
+func (st *SafeTrie) SetWeighted(path string, targets []string, weights []int) error {
 	if path == "" {
 		return errors.New("path is empty")
 	}
 
-	newTarget, err := st.NewTarget(targets)
+	newTarget, err := st.NewWeightedTarget(targets, weights)
 	if err != nil {
 		return errors.Wrap(err, "failed to create Target")
 	}
@@ -130,13 +135,36 @@ func (st *SafeTrie) IsEmpty() bool {
 // NewTarget returns a Target based on the length of the input slice
 func (st *SafeTrie) NewTarget(inputs []string) (Target, error) {
 	switch len(inputs) {
+	case 0:
+		return nil, errors.New("unexpected input length")
 	case 1:
 		return SingleTarget(inputs[0]), nil
 	case 2:
 		return PairTarget{inputs[0], inputs[1]}, nil
 	default:
+		return MultipleTarget(append([]string{}, inputs...)), nil
+	}
+}
+
+// NewWeightedTarget returns a Target routing to the inputs according to the given weights, or an unweighted Target if
+// no weights are given
+func (st *SafeTrie) NewWeightedTarget(inputs []string, weights []int) (Target, error) {
+	if weights == nil {
+		return st.NewTarget(inputs)
+	}
+
+	if len(inputs) == 0 {
 		return nil, errors.New("unexpected input length")
 	}
+
+	if err := ValidateTargetWeights(inputs, weights); err != nil {
+		return nil, err
+	}
+
+	return WeightedTarget{
+		Names:   append([]string{}, inputs...),
+		Weights: append([]int{}, weights...),
+	}, nil
 }
 
 // ----- implementations for Target interface -----
@@ -144,12 +172,14 @@ func (st *SafeTrie) NewTarget(inputs []string) (Target, error) {
 type SingleTarget string
 
 func (s SingleTarget) Equal(otherTarget Target) bool {
-	otherSingleTarget, ok := otherTarget.(SingleTarget)
-	if !ok {
+	switch typedOtherTarget := otherTarget.(type) {
+	case SingleTarget:
+		return string(s) == string(typedOtherTarget)
+	case WeightedTarget:
+		return typedOtherTarget.Equal(s)
+	default:
 		return false
 	}
-
-	return string(s) == string(otherSingleTarget)
 }
 
 func (s SingleTarget) ToSliceString() []string {
@@ -159,6 +189,10 @@ func (s SingleTarget) ToSliceString() []string {
 type PairTarget [2]string
 
 func (p PairTarget) Equal(otherTarget Target) bool {
+	if weightedTarget, ok := otherTarget.(WeightedTarget); ok {
+		return weightedTarget.Equal(p)
+	}
+
 	target, ok := otherTarget.(PairTarget)
 	if !ok {
 		return false
@@ -178,3 +212,94 @@ func (p PairTarget) Equal(otherTarget Target) bool {
 func (p PairTarget) ToSliceString() []string {
 	return []string{p[0], p[1]}
 }
+
+type MultipleTarget []string
+
+func (m MultipleTarget) Equal(otherTarget Target) bool {
+	switch otherTarget.(type) {
+	case MultipleTarget, WeightedTarget:
+		return equalTargetNames(m, otherTarget.ToSliceString())
+	default:
+		return false
+	}
+}
+
+func (m MultipleTarget) ToSliceString() []string {
+	return append([]string{}, m...)
+}
+
+// WeightedTarget routes to its targets according to their weights. it equals any target of this package with the same
+// target names, regardless of the weights, so that it can be deleted by its target names. the other targets of this
+// package equal it the same way, so that the comparison does not depend on its order
+type WeightedTarget struct {
+	Names   []string
+	Weights []int
+}
+
+func (w WeightedTarget) Equal(otherTarget Target) bool {
+	switch otherTarget.(type) {
+	case SingleTarget, PairTarget, MultipleTarget, WeightedTarget:
+		return equalTargetNames(w.Names, otherTarget.ToSliceString())
+	default:
+		return false
+	}
+}
+
+func (w WeightedTarget) ToSliceString() []string {
+	return append([]string{}, w.Names...)
+}
+
+func (w WeightedTarget) GetWeights() []int {
+	return append([]int{}, w.Weights...)
+}
+
+// GetTargetWeights returns the routing weights of the target, in the same order as ToSliceString, or nil if the target
+// is not weighted
+func GetTargetWeights(target Target) []int {
+	if targetWithWeights, ok := target.(TargetWithWeights); ok {
+		return targetWithWeights.GetWeights()
+	}
+	return nil
+}
+
+// ValidateTargetWeights returns an error unless there is a weight per target, none of the weights is negative and
+// their total is positive
+func ValidateTargetWeights(targets []string, weights []int) error {
+	if len(weights) != len(targets) {
+		return errors.Errorf("expected %d weights, got %d", len(targets), len(weights))
+	}
+
+	totalWeight := 0
+	for _, weight := range weights {
+		if weight < 0 {
+			return errors.Errorf("weight must not be negative, got %d", weight)
+		}
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+
+	return nil
+}
+
+// equalTargetNames returns true if both slices hold the same target names, in any order
+func equalTargetNames(targetNames, otherTargetNames []string) bool {
+	if len(targetNames) != len(otherTargetNames) {
+		return false
+	}
+
+	targetNamesCount := map[string]int{}
+	for _, targetName := range targetNames {
+		targetNamesCount[targetName]++
+	}
+	for _, otherTargetName := range otherTargetNames {
+		if targetNamesCount[otherTargetName] == 0 {
+			return false
+		}
+		targetNamesCount[otherTargetName]--
+	}
+
+	return true
+}
@@ -174,6 +174,14 @@ func (suite *SafeTrieTestSuite) TestPathTreeSet() {
 				{path: "/path/to/targets", targets: []string{"test-target", "test-TARGET"}},
 			},
 			expectedResult: map[string]Target{"/path/to/targets": PairTarget{"test-target", "test-TARGET"}},
+		}, {
+			name: "more than two targets",
+			args: []safeTrieTestArgs{
+				{path: "/path/to/targets", targets: []string{"test-target1", "test-target2", "test-target3"}},
+			},
+			expectedResult: map[string]Target{
+				"/path/to/targets": MultipleTarget{"test-target1", "test-target2", "test-target3"},
+			},
 		}, {
 			name: "path with numbers and hyphens",
 			args: []safeTrieTestArgs{
@@ -441,6 +449,71 @@ func (suite *SafeTrieTestSuite) generateSafeTrieForTest(initialSafeTrieState []s
 	return safeTrie
 }
 
+func (suite *SafeTrieTestSuite) TestNewWeightedTarget() {
+	for _, testCase := range []struct {
+		name           string
+		targets        []string
+		weights        []int
+		expectedTarget Target
+		errorMessage   string
+	}{
+		{
+			name:           "no weights",
+			targets:        []string{"test-target1", "test-target2"},
+			expectedTarget: PairTarget{"test-target1", "test-target2"},
+		}, {
+			name:    "weighted targets",
+			targets: []string{"test-target1", "test-target2", "test-target3"},
+			weights: []int{80, 10, 10},
+			expectedTarget: WeightedTarget{
+				Names:   []string{"test-target1", "test-target2", "test-target3"},
+				Weights: []int{80, 10, 10},
+			},
+		}, {
+			name:         "weights length mismatch",
+			targets:      []string{"test-target1", "test-target2"},
+			weights:      []int{100},
+			errorMessage: "expected 2 weights, got 1",
+		}, {
+			name:         "negative weight",
+			targets:      []string{"test-target1", "test-target2"},
+			weights:      []int{110, -10},
+			errorMessage: "weight must not be negative",
+		}, {
+			name:         "zero total weight",
+			targets:      []string{"test-target1", "test-target2"},
+			weights:      []int{0, 0},
+			errorMessage: "at least one weight must be positive",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			target, err := NewSafeTrie().NewWeightedTarget(testCase.targets, testCase.weights)
+			if testCase.errorMessage != "" {
+				suite.Require().ErrorContains(err, testCase.errorMessage)
+				return
+			}
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedTarget, target)
+			suite.Require().Equal(testCase.weights, GetTargetWeights(target))
+		})
+	}
+}
+
+func (suite *SafeTrieTestSuite) TestPathTreeDeleteWeighted() {
+	testSafeTrie := NewSafeTrie()
+	err := testSafeTrie.SetWeighted("/path/to/targets", []string{"test-target1", "test-target2"}, []int{90, 10})
+	suite.Require().NoError(err)
+
+	target, err := testSafeTrie.Get("/path/to/targets")
+	suite.Require().NoError(err)
+	suite.Require().Equal([]int{90, 10}, GetTargetWeights(target))
+
+	// weighted targets are deleted by their target names
+	err = testSafeTrie.Delete("/path/to/targets", []string{"test-target2", "test-target1"})
+	suite.Require().NoError(err)
+	suite.Require().True(testSafeTrie.IsEmpty())
+}
+
 func TestSafeTrieSuite(t *testing.T) {
 	suite.Run(t, new(SafeTrieTestSuite))
 }
@@ -589,3 +662,109 @@ func (suite *PairTargetTestSuite) TestToSliceString() {
 func TestTargetsTestSuite(t *testing.T) {
 	suite.Run(t, new(PairTargetTestSuite))
 }
+
+// --- MultipleTargetTestSuite ---
+type MultipleTargetTestSuite struct {
+	suite.Suite
+}
+
+func (suite *MultipleTargetTestSuite) TestEqual() {
+	testCases := []struct {
+		name           string
+		target         Target
+		expectedResult bool
+	}{
+		{
+			name:           "Equal match",
+			target:         MultipleTarget{"test-target1", "test-target2", "test-target3"},
+			expectedResult: true,
+		}, {
+			name:           "Equal match different order",
+			target:         MultipleTarget{"test-target3", "test-target1", "test-target2"},
+			expectedResult: true,
+		}, {
+			name:           "Equal no match",
+			target:         MultipleTarget{"test-target1", "test-target2", "test-target4"},
+			expectedResult: false,
+		}, {
+			name:           "Equal subset no match",
+			target:         PairTarget{"test-target1", "test-target2"},
+			expectedResult: false,
+		}, {
+			name: "Equal weighted targets with the same names",
+			target: WeightedTarget{
+				Names:   []string{"test-target1", "test-target2", "test-target3"},
+				Weights: []int{1, 1, 1},
+			},
+			expectedResult: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+			testTargets := MultipleTarget{"test-target1", "test-target2", "test-target3"}
+			result := testTargets.Equal(testCase.target)
+			suite.Equal(testCase.expectedResult, result)
+		})
+	}
+}
+
+// TestMultipleTargetTestSuite runs the test suite
+func TestMultipleTargetTestSuite(t *testing.T) {
+	suite.Run(t, new(MultipleTargetTestSuite))
+}
+
+// --- WeightedTargetTestSuite ---
+type WeightedTargetTestSuite struct {
+	suite.Suite
+}
+
+func (suite *WeightedTargetTestSuite) TestEqual() {
+	testCases := []struct {
+		name           string
+		target         Target
+		weightedTarget WeightedTarget
+		expectedResult bool
+	}{
+		{
+			name:           "Equal single target",
+			target:         SingleTarget("test-target1"),
+			weightedTarget: WeightedTarget{Names: []string{"test-target1"}, Weights: []int{1}},
+			expectedResult: true,
+		}, {
+			name:           "Equal pair target different order",
+			target:         PairTarget{"test-target1", "test-target2"},
+			weightedTarget: WeightedTarget{Names: []string{"test-target2", "test-target1"}, Weights: []int{90, 10}},
+			expectedResult: true,
+		}, {
+			name:           "Equal multiple target",
+			target:         MultipleTarget{"test-target1", "test-target2", "test-target3"},
+			weightedTarget: WeightedTarget{Names: []string{"test-target1", "test-target2", "test-target3"}, Weights: []int{1, 1, 1}},
+			expectedResult: true,
+		}, {
+			name:           "Equal weighted target different weights",
+			target:         WeightedTarget{Names: []string{"test-target1", "test-target2"}, Weights: []int{50, 50}},
+			weightedTarget: WeightedTarget{Names: []string{"test-target1", "test-target2"}, Weights: []int{90, 10}},
+			expectedResult: true,
+		}, {
+			name:           "Equal pair target no match",
+			target:         PairTarget{"test-target1", "test-target3"},
+			weightedTarget: WeightedTarget{Names: []string{"test-target1", "test-target2"}, Weights: []int{90, 10}},
+			expectedResult: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		suite.Run(testCase.name, func() {
+
+			// the comparison does not depend on the order of the targets
+			suite.Equal(testCase.expectedResult, testCase.weightedTarget.Equal(testCase.target))
+			suite.Equal(testCase.expectedResult, testCase.target.Equal(testCase.weightedTarget))
+		})
+	}
+}
+
+// TestWeightedTargetTestSuite runs the test suite
+func TestWeightedTargetTestSuite(t *testing.T) {
+	suite.Run(t, new(WeightedTargetTestSuite))
+}
@@ -25,6 +25,16 @@ type IngressHostCacheReader interface {
 	Get(host string, path string) ([]string, error)
 }
 
+// WeightedIngressHostCacheReader is implemented by caches that also hold the routing weights of the targets. It is kept
+// apart from IngressHostCacheReader so that the existing implementations of the latter remain valid
+type WeightedIngressHostCacheReader interface {
+	IngressHostCacheReader
+
+	// GetWeighted retrieves all target names for the given host and path, along with their routing weights in the same
+	// order. The weights are nil if the targets are not weighted
+	GetWeighted(host string, path string) ([]string, []int, error)
+}
+
 type IngressHostCache interface {
 	IngressHostCacheReader
 
@@ -35,6 +45,15 @@ type IngressHostCache interface {
 	Delete(host string, path string, targets []string) error
 }
 
+// WeightedIngressHostCache is an IngressHostCache that also holds the routing weights of the targets
+type WeightedIngressHostCache interface {
+	IngressHostCache
+	WeightedIngressHostCacheReader
+
+	// SetWeighted is like Set, with the routing weights of the targets (nil for unweighted targets)
+	SetWeighted(host string, path string, targets []string, weights []int) error
+}
+
 type IngressHostsTree interface {
 	// Set sets the targets for a given path. Will overwrite existing values if the path already exists
 	Set(path string, targets []string) error
@@ -49,6 +68,14 @@ type IngressHostsTree interface {
 	IsEmpty() bool
 }
 
+// WeightedIngressHostsTree is an IngressHostsTree that also holds the routing weights of the targets
+type WeightedIngressHostsTree interface {
+	IngressHostsTree
+
+	// SetWeighted is like Set, with the routing weights of the targets (nil for unweighted targets)
+	SetWeighted(path string, targets []string, weights []int) error
+}
+
 // Target defines the trie.PathTrie value
 type Target interface {
 	// Equal returns true if the otherTarget is equal to the current target
@@ -57,3 +84,12 @@ type Target interface {
 	// ToSliceString returns a slice of targets
 	ToSliceString() []string
 }
+
+// TargetWithWeights is implemented by targets routed according to their weights. It is kept apart from Target so that
+// the existing implementations of the latter remain valid
+type TargetWithWeights interface {
+	Target
+
+	// GetWeights returns the routing weights of the targets, in the same order as ToSliceString
+	GetWeights() []int
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package kube
+
+import (
+	"context"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ServiceEndpointsChangedCallback is called with the name of a service whose ready endpoints changed or were removed
+type ServiceEndpointsChangedCallback func(serviceName string)
+
+// EndpointSliceWatcher watches for changes in the ready endpoints of services. When a resource is scaled to zero, its
+// service either loses its endpoints or is re-routed to the DLX, both of which change the service ready endpoints
+type EndpointSliceWatcher struct {
+	ctx                     context.Context
+	cancel                  context.CancelFunc
+	logger                  logger.Logger
+	factory                 informers.SharedInformerFactory
+	informer                cache.SharedIndexInformer
+	endpointsChangeCallback ServiceEndpointsChangedCallback
+}
+
+func NewEndpointSliceWatcher(
+	dlxCtx context.Context,
+	dlxLogger logger.Logger,
+	kubeClient kubernetes.Interface,
+	endpointsChangeCallback ServiceEndpointsChangedCallback,
+	resyncInterval scalertypes.Duration,
+	namespace string,
+) (*EndpointSliceWatcher, error) {
+	if resyncInterval.Duration == 0 {
+		resyncInterval = scalertypes.Duration{Duration: scalertypes.DefaultResyncInterval}
+	}
+
+	ctxWithCancel, cancel := context.WithCancel(dlxCtx)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		kubeClient,
+		resyncInterval.Duration,
+		informers.WithNamespace(namespace),
+	)
+	endpointSliceInformer := factory.Discovery().V1().EndpointSlices().Informer()
+
+	endpointSliceWatcher := &EndpointSliceWatcher{
+		ctx:                     ctxWithCancel,
+		cancel:                  cancel,
+		logger:                  dlxLogger.GetChild("endpoint-slice-watcher"),
+		factory:                 factory,
+		informer:                endpointSliceInformer,
+		endpointsChangeCallback: endpointsChangeCallback,
+	}
+
+	if _, err := endpointSliceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: endpointSliceWatcher.UpdateHandler,
+		DeleteFunc: endpointSliceWatcher.DeleteHandler,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to add event handlers to informer")
+	}
+
+	return endpointSliceWatcher, nil
+}
+
+func (ew *EndpointSliceWatcher) Start() error {
+	ew.logger.Info("Starting endpoint slice watcher")
+	ew.factory.Start(ew.ctx.Done())
+
+	if !cache.WaitForCacheSync(ew.ctx.Done(), ew.informer.HasSynced) {
+		return errors.New("Failed to sync endpoint slice cache")
+	}
+
+	ew.logger.Info("Endpoint slice watcher started successfully")
+
+	return nil
+}
+
+func (ew *EndpointSliceWatcher) Stop() {
+	ew.logger.Info("Stopping endpoint slice watcher")
+	ew.cancel()
+	ew.factory.Shutdown()
+}
+
+// --- ResourceEventHandler methods ---
+
+func (ew *EndpointSliceWatcher) UpdateHandler(oldObj, newObj interface{}) {
+	oldEndpointSlice, ok := oldObj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		ew.logger.DebugWith("Failed to cast old object to EndpointSlice",
+			"object", oldObj)
+		return
+	}
+
+	newEndpointSlice, ok := newObj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		ew.logger.DebugWith("Failed to cast new object to EndpointSlice",
+			"object", newObj)
+		return
+	}
+
+	// skip periodic informer resyncs
+	if oldEndpointSlice.ResourceVersion == newEndpointSlice.ResourceVersion {
+		return
+	}
+
+	if ew.readyAddressesEqual(oldEndpointSlice, newEndpointSlice) {
+		return
+	}
+
+	ew.notifyEndpointsChanged(newEndpointSlice)
+}
+
+func (ew *EndpointSliceWatcher) DeleteHandler(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	endpointSlice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		ew.logger.DebugWith("Failed to cast object to EndpointSlice",
+			"object", obj)
+		return
+	}
+
+	ew.notifyEndpointsChanged(endpointSlice)
+}
+
+// --- internal methods ---
+
+func (ew *EndpointSliceWatcher) notifyEndpointsChanged(endpointSlice *discoveryv1.EndpointSlice) {
+	serviceName := endpointSlice.Labels[discoveryv1.LabelServiceName]
+	if serviceName == "" {
+		return
+	}
+
+	ew.logger.DebugWith("Service ready endpoints changed",
+		"serviceName", serviceName,
+		"endpointSlice", endpointSlice.Name)
+	ew.endpointsChangeCallback(serviceName)
+}
+
+func (ew *EndpointSliceWatcher) readyAddressesEqual(oldEndpointSlice, newEndpointSlice *discoveryv1.EndpointSlice) bool {
+	oldAddresses := ew.getReadyAddresses(oldEndpointSlice)
+	newAddresses := ew.getReadyAddresses(newEndpointSlice)
+	if len(oldAddresses) != len(newAddresses) {
+		return false
+	}
+
+	for address := range oldAddresses {
+		if _, found := newAddresses[address]; !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ew *EndpointSliceWatcher) getReadyAddresses(endpointSlice *discoveryv1.EndpointSlice) map[string]struct{} {
+	readyAddresses := map[string]struct{}{}
+	for _, endpoint := range endpointSlice.Endpoints {
+
+		// a nil ready condition should be interpreted as ready
+		if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+			continue
+		}
+
+		for _, address := range endpoint.Addresses {
+			readyAddresses[address] = struct{}{}
+		}
+	}
+
+	return readyAddresses
+}
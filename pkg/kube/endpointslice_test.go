@@ -0,0 +1,210 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+type EndpointSliceWatcherTestSuite struct {
+	suite.Suite
+	logger          logger.Logger
+	kubeClientSet   *fake.Clientset
+	changedServices []string
+}
+
+func (suite *EndpointSliceWatcherTestSuite) SetupSuite() {
+	suite.kubeClientSet = fake.NewSimpleClientset()
+}
+
+func (suite *EndpointSliceWatcherTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+	suite.changedServices = nil
+}
+
+func (suite *EndpointSliceWatcherTestSuite) TestUpdateHandler() {
+	for _, testCase := range []struct {
+		name                    string
+		oldReadyAddresses       []string
+		oldNotReadyAddresses    []string
+		newReadyAddresses       []string
+		newNotReadyAddresses    []string
+		oldObjVersion           string
+		newObjVersion           string
+		expectedChangedServices []string
+	}{
+		{
+			name:                    "Endpoints removed",
+			oldReadyAddresses:       []string{"10.0.0.1"},
+			oldObjVersion:           "1",
+			newObjVersion:           "2",
+			expectedChangedServices: []string{"test-service"},
+		}, {
+			name:                    "Endpoint became not ready",
+			oldReadyAddresses:       []string{"10.0.0.1"},
+			newNotReadyAddresses:    []string{"10.0.0.1"},
+			oldObjVersion:           "1",
+			newObjVersion:           "2",
+			expectedChangedServices: []string{"test-service"},
+		}, {
+			name:                    "Endpoint replaced",
+			oldReadyAddresses:       []string{"10.0.0.1"},
+			newReadyAddresses:       []string{"10.0.0.2"},
+			oldObjVersion:           "1",
+			newObjVersion:           "2",
+			expectedChangedServices: []string{"test-service"},
+		}, {
+			name:                 "Not ready endpoint added - no change in ready endpoints",
+			oldReadyAddresses:    []string{"10.0.0.1"},
+			newReadyAddresses:    []string{"10.0.0.1"},
+			newNotReadyAddresses: []string{"10.0.0.2"},
+			oldObjVersion:        "1",
+			newObjVersion:        "2",
+		}, {
+			name:              "Same ResourceVersion - resync is ignored",
+			oldReadyAddresses: []string{"10.0.0.1"},
+			oldObjVersion:     "1",
+			newObjVersion:     "1",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.changedServices = nil
+			testEndpointSliceWatcher, err := suite.createTestEndpointSliceWatcher()
+			suite.Require().NoError(err)
+
+			oldEndpointSlice := suite.createDummyEndpointSlice("test-service",
+				testCase.oldObjVersion,
+				testCase.oldReadyAddresses,
+				testCase.oldNotReadyAddresses)
+			newEndpointSlice := suite.createDummyEndpointSlice("test-service",
+				testCase.newObjVersion,
+				testCase.newReadyAddresses,
+				testCase.newNotReadyAddresses)
+
+			testEndpointSliceWatcher.UpdateHandler(oldEndpointSlice, newEndpointSlice)
+			suite.Require().Equal(testCase.expectedChangedServices, suite.changedServices)
+		})
+	}
+}
+
+func (suite *EndpointSliceWatcherTestSuite) TestDeleteHandler() {
+	for _, testCase := range []struct {
+		name                    string
+		obj                     interface{}
+		expectedChangedServices []string
+	}{
+		{
+			name:                    "Delete endpoint slice",
+			obj:                     suite.createDummyEndpointSlice("test-service", "1", []string{"10.0.0.1"}, nil),
+			expectedChangedServices: []string{"test-service"},
+		}, {
+			name: "Delete endpoint slice - tombstone",
+			obj: cache.DeletedFinalStateUnknown{
+				Key: "test-namespace/test-service-abcde",
+				Obj: suite.createDummyEndpointSlice("test-service", "1", []string{"10.0.0.1"}, nil),
+			},
+			expectedChangedServices: []string{"test-service"},
+		}, {
+			name: "Delete endpoint slice - no service label",
+			obj:  suite.createDummyEndpointSlice("", "1", []string{"10.0.0.1"}, nil),
+		}, {
+			name: "Delete unknown object",
+			obj:  "not-an-endpoint-slice",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.changedServices = nil
+			testEndpointSliceWatcher, err := suite.createTestEndpointSliceWatcher()
+			suite.Require().NoError(err)
+
+			testEndpointSliceWatcher.DeleteHandler(testCase.obj)
+			suite.Require().Equal(testCase.expectedChangedServices, suite.changedServices)
+		})
+	}
+}
+
+// --- EndpointSliceWatcherTestSuite suite methods ---
+
+func (suite *EndpointSliceWatcherTestSuite) createTestEndpointSliceWatcher() (*EndpointSliceWatcher, error) {
+	return NewEndpointSliceWatcher(context.Background(),
+		suite.logger,
+		suite.kubeClientSet,
+		func(serviceName string) {
+			suite.changedServices = append(suite.changedServices, serviceName)
+		},
+		scalertypes.Duration{},
+		"test-namespace",
+	)
+}
+
+func (suite *EndpointSliceWatcherTestSuite) createDummyEndpointSlice(serviceName, version string,
+	readyAddresses []string,
+	notReadyAddresses []string) *discoveryv1.EndpointSlice {
+	labels := map[string]string{}
+	if serviceName != "" {
+		labels[discoveryv1.LabelServiceName] = serviceName
+	}
+
+	ready, notReady := true, false
+	var endpoints []discoveryv1.Endpoint
+	for _, address := range readyAddresses {
+		endpoints = append(endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{address},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		})
+	}
+	for _, address := range notReadyAddresses {
+		endpoints = append(endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{address},
+			Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+		})
+	}
+
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            serviceName + "-abcde",
+			Namespace:       "test-namespace",
+			ResourceVersion: version,
+			Labels:          labels,
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   endpoints,
+	}
+}
+
+// TestEndpointSliceWatcherTestSuite runs the test suite
+func TestEndpointSliceWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(EndpointSliceWatcherTestSuite))
+}
@@ -22,6 +22,7 @@ package kube
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/v3io/scaler/pkg/ingresscache"
 	"github.com/v3io/scaler/pkg/scalertypes"
@@ -35,11 +36,20 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
+const (
+	nginxCanaryWeightAnnotation      = "nginx.ingress.kubernetes.io/canary-weight"
+	nginxCanaryWeightTotalAnnotation = "nginx.ingress.kubernetes.io/canary-weight-total"
+	defaultNginxCanaryWeightTotal    = 100
+)
+
 type IngressValue struct {
 	Name    string
 	Host    string
 	Path    string
 	Targets []string
+
+	// routing weights of the targets, in the same order (nil when unweighted)
+	Weights []int
 }
 
 // IngressWatcher watches for changes in Kubernetes Ingress resources and updates the ingress cache accordingly
@@ -47,10 +57,13 @@ type IngressWatcher struct {
 	ctx                    context.Context
 	cancel                 context.CancelFunc
 	logger                 logger.Logger
-	cache                  ingresscache.IngressHostCache
+	cache                  ingresscache.WeightedIngressHostCache
 	factory                informers.SharedInformerFactory
 	informer               cache.SharedIndexInformer
 	resolveTargetsCallback scalertypes.ResolveTargetsFromIngressCallback
+
+	// optional
+	resolveTargetWeightsCallback scalertypes.ResolveTargetWeightsFromIngressCallback
 }
 
 func NewIngressWatcher(
@@ -58,6 +71,7 @@ func NewIngressWatcher(
 	dlxLogger logger.Logger,
 	kubeClient kubernetes.Interface,
 	resolveTargetsCallback scalertypes.ResolveTargetsFromIngressCallback,
+	resolveTargetWeightsCallback scalertypes.ResolveTargetWeightsFromIngressCallback,
 	resyncInterval scalertypes.Duration,
 	namespace string,
 	labelSelector string,
@@ -79,13 +93,14 @@ func NewIngressWatcher(
 	ingressInformer := factory.Networking().V1().Ingresses().Informer()
 
 	ingressWatcher := &IngressWatcher{
-		ctx:                    ctxWithCancel,
-		cancel:                 cancel,
-		logger:                 dlxLogger.GetChild("watcher"),
-		cache:                  ingresscache.NewIngressCache(dlxLogger),
-		factory:                factory,
-		informer:               ingressInformer,
-		resolveTargetsCallback: resolveTargetsCallback,
+		ctx:                          ctxWithCancel,
+		cancel:                       cancel,
+		logger:                       dlxLogger.GetChild("watcher"),
+		cache:                        ingresscache.NewIngressCache(dlxLogger),
+		factory:                      factory,
+		informer:                     ingressInformer,
+		resolveTargetsCallback:       resolveTargetsCallback,
+		resolveTargetWeightsCallback: resolveTargetWeightsCallback,
 	}
 
 	if _, err := ingressInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -133,14 +148,15 @@ func (iw *IngressWatcher) AddHandler(obj interface{}) {
 		return
 	}
 
-	if err := iw.cache.Set(ingress.Host, ingress.Path, ingress.Targets); err != nil {
+	if err := iw.cache.SetWeighted(ingress.Host, ingress.Path, ingress.Targets, ingress.Weights); err != nil {
 		iw.logger.WarnWith("Add ingress handler failure - failed to add the new value to ingress cache",
 			"error", err.Error(),
 			"object", obj,
 			"ingressName", ingress.Name,
 			"host", ingress.Host,
 			"path", ingress.Path,
-			"targets", ingress.Targets)
+			"targets", ingress.Targets,
+			"weights", ingress.Weights)
 		return
 	}
 
@@ -148,7 +164,8 @@ func (iw *IngressWatcher) AddHandler(obj interface{}) {
 		"ingressName", ingress.Name,
 		"host", ingress.Host,
 		"path", ingress.Path,
-		"targets", ingress.Targets)
+		"targets", ingress.Targets,
+		"weights", ingress.Weights)
 }
 
 func (iw *IngressWatcher) UpdateHandler(oldObj, newObj interface{}) {
@@ -199,14 +216,15 @@ func (iw *IngressWatcher) UpdateHandler(oldObj, newObj interface{}) {
 		}
 	}
 
-	if err := iw.cache.Set(newIngress.Host, newIngress.Path, newIngress.Targets); err != nil {
+	if err := iw.cache.SetWeighted(newIngress.Host, newIngress.Path, newIngress.Targets, newIngress.Weights); err != nil {
 		iw.logger.WarnWith("Update ingress handler failure - failed to add the new value",
 			"error", err.Error(),
 			"object", newObj,
 			"ingressName", newIngress.Name,
 			"host", newIngress.Host,
 			"path", newIngress.Path,
-			"targets", newIngress.Targets)
+			"targets", newIngress.Targets,
+			"weights", newIngress.Weights)
 		return
 	}
 
@@ -214,7 +232,8 @@ func (iw *IngressWatcher) UpdateHandler(oldObj, newObj interface{}) {
 		"ingressName", newIngress.Name,
 		"host", newIngress.Host,
 		"path", newIngress.Path,
-		"targets", newIngress.Targets)
+		"targets", newIngress.Targets,
+		"weights", newIngress.Weights)
 }
 
 func (iw *IngressWatcher) DeleteHandler(obj interface{}) {
@@ -232,7 +251,8 @@ func (iw *IngressWatcher) DeleteHandler(obj interface{}) {
 			"ingressName", ingress.Name,
 			"host", ingress.Host,
 			"path", ingress.Path,
-			"targets", ingress.Targets)
+			"targets", ingress.Targets,
+			"weights", ingress.Weights)
 		return
 	}
 
@@ -240,7 +260,8 @@ func (iw *IngressWatcher) DeleteHandler(obj interface{}) {
 		"ingressName", ingress.Name,
 		"host", ingress.Host,
 		"path", ingress.Path,
-		"targets", ingress.Targets)
+		"targets", ingress.Targets,
+		"weights", ingress.Weights)
 }
 
 // --- internal methods ---
@@ -275,10 +296,80 @@ func (iw *IngressWatcher) extractValuesFromIngressResource(obj interface{}) (*In
 		Host:    host,
 		Path:    path,
 		Targets: targets,
+		Weights: iw.resolveTargetWeights(ingress, targets),
 		Name:    ingress.Name,
 	}, nil
 }
 
+// resolveTargetWeights returns the routing weights of the ingress targets, or nil if the ingress does not define valid
+// weights - in which case the targets are routed to according to the multi target strategy
+func (iw *IngressWatcher) resolveTargetWeights(ingress *networkingv1.Ingress, targets []string) []int {
+	var weights []int
+	var err error
+	if iw.resolveTargetWeightsCallback != nil {
+		if weights, err = iw.resolveTargetWeightsCallback(ingress, targets); err != nil {
+			iw.logger.WarnWith("Failed to resolve target weights from ingress, ignoring weights",
+				"ingressName", ingress.Name,
+				"targets", targets,
+				"error", err.Error())
+			return nil
+		}
+	}
+
+	if weights == nil {
+		if weights, err = iw.getNginxCanaryWeights(ingress, targets); err != nil {
+			iw.logger.WarnWith("Failed to get canary weights from ingress annotations, ignoring weights",
+				"ingressName", ingress.Name,
+				"targets", targets,
+				"error", err.Error())
+			return nil
+		}
+	}
+
+	// invalid weights would fail caching the whole route, so the route is cached unweighted instead
+	if weights != nil {
+		if err := ingresscache.ValidateTargetWeights(targets, weights); err != nil {
+			iw.logger.WarnWith("Invalid target weights, ignoring weights",
+				"ingressName", ingress.Name,
+				"targets", targets,
+				"weights", weights,
+				"error", err.Error())
+			return nil
+		}
+	}
+
+	return weights
+}
+
+// getNginxCanaryWeights returns the weights of a primary and a canary target, in this order, from the nginx canary
+// weight annotations. returns nil if the ingress has no canary weight annotation or does not have exactly two targets
+func (iw *IngressWatcher) getNginxCanaryWeights(ingress *networkingv1.Ingress, targets []string) ([]int, error) {
+	canaryWeightValue, found := ingress.Annotations[nginxCanaryWeightAnnotation]
+	if !found || len(targets) != 2 {
+		return nil, nil
+	}
+
+	canaryWeight, err := strconv.Atoi(canaryWeightValue)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid canary weight: %s", canaryWeightValue)
+	}
+
+	canaryWeightTotal := defaultNginxCanaryWeightTotal
+	if canaryWeightTotalValue, found := ingress.Annotations[nginxCanaryWeightTotalAnnotation]; found {
+		if canaryWeightTotal, err = strconv.Atoi(canaryWeightTotalValue); err != nil {
+			return nil, errors.Wrapf(err, "Invalid canary weight total: %s", canaryWeightTotalValue)
+		}
+	}
+
+	if canaryWeight < 0 || canaryWeightTotal <= 0 {
+		return nil, errors.Errorf("Invalid canary weight %d of total %d", canaryWeight, canaryWeightTotal)
+	}
+
+	// like nginx, a canary weight above the total sends all traffic to the canary
+	canaryWeight = min(canaryWeight, canaryWeightTotal)
+	return []int{canaryWeightTotal - canaryWeight, canaryWeight}, nil
+}
+
 func (iw *IngressWatcher) getHostFromIngress(ingress *networkingv1.Ingress) (string, error) {
 	rule, err := iw.getFirstRule(ingress)
 	if err != nil || rule == nil {
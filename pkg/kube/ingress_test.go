@@ -551,6 +551,92 @@ func (suite *IngressWatcherTestSuite) TestGetHostFromIngress() {
 	}
 }
 
+func (suite *IngressWatcherTestSuite) TestResolveTargetWeights() {
+	for _, testCase := range []struct {
+		name            string
+		annotations     map[string]string
+		targets         []string
+		weightsCallback scalertypes.ResolveTargetWeightsFromIngressCallback
+		expectedWeights []int
+	}{
+		{
+			name:    "No weights",
+			targets: []string{"test-targets-name-1", "test-targets-name-2"},
+		}, {
+			name:            "Canary weight annotation",
+			annotations:     map[string]string{"nginx.ingress.kubernetes.io/canary-weight": "10"},
+			targets:         []string{"test-targets-name-1", "test-targets-name-2"},
+			expectedWeights: []int{90, 10},
+		}, {
+			name: "Canary weight annotation with total",
+			annotations: map[string]string{
+				"nginx.ingress.kubernetes.io/canary-weight":       "30",
+				"nginx.ingress.kubernetes.io/canary-weight-total": "1000",
+			},
+			targets:         []string{"test-targets-name-1", "test-targets-name-2"},
+			expectedWeights: []int{970, 30},
+		}, {
+			name:            "Canary weight above total",
+			annotations:     map[string]string{"nginx.ingress.kubernetes.io/canary-weight": "150"},
+			targets:         []string{"test-targets-name-1", "test-targets-name-2"},
+			expectedWeights: []int{0, 100},
+		}, {
+			name:        "Invalid canary weight annotation",
+			annotations: map[string]string{"nginx.ingress.kubernetes.io/canary-weight": "ten"},
+			targets:     []string{"test-targets-name-1", "test-targets-name-2"},
+		}, {
+			name:        "Canary weight annotation with a single target",
+			annotations: map[string]string{"nginx.ingress.kubernetes.io/canary-weight": "10"},
+			targets:     []string{"test-targets-name-1"},
+		}, {
+			name:        "Weights callback takes precedence",
+			annotations: map[string]string{"nginx.ingress.kubernetes.io/canary-weight": "10"},
+			targets:     []string{"test-targets-name-1", "test-targets-name-2", "test-targets-name-3"},
+			weightsCallback: func(ingress *networkingv1.Ingress, targets []string) ([]int, error) {
+				return []int{50, 30, 20}, nil
+			},
+			expectedWeights: []int{50, 30, 20},
+		}, {
+			name:    "Weights callback with mismatching amount of weights",
+			targets: []string{"test-targets-name-1", "test-targets-name-2"},
+			weightsCallback: func(ingress *networkingv1.Ingress, targets []string) ([]int, error) {
+				return []int{100}, nil
+			},
+		}, {
+			name:    "Weights callback with a negative weight",
+			targets: []string{"test-targets-name-1", "test-targets-name-2"},
+			weightsCallback: func(ingress *networkingv1.Ingress, targets []string) ([]int, error) {
+				return []int{110, -10}, nil
+			},
+		}, {
+			name:    "Weights callback with a zero total",
+			targets: []string{"test-targets-name-1", "test-targets-name-2"},
+			weightsCallback: func(ingress *networkingv1.Ingress, targets []string) ([]int, error) {
+				return []int{0, 0}, nil
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			testIngressWatcher, err := suite.createTestIngressWatcher()
+			suite.Require().NoError(err)
+			testIngressWatcher.resolveTargetWeightsCallback = testCase.weightsCallback
+
+			testIngress := suite.createDummyIngress("www.example.com", "/test/path", "1", testCase.targets)
+			testIngress.Annotations = testCase.annotations
+
+			ingressValue, err := testIngressWatcher.extractValuesFromIngressResource(testIngress)
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedWeights, ingressValue.Weights)
+
+			// the route is cached even when its weights are ignored
+			testIngressWatcher.AddHandler(testIngress)
+			cachedTargets, err := testIngressWatcher.cache.Get("www.example.com", "/test/path")
+			suite.Require().NoError(err)
+			suite.Require().ElementsMatch(testCase.targets, cachedTargets)
+		})
+	}
+}
+
 // --- IngressWatcherTestSuite suite methods ---
 
 // Create a dummy IngressWatcher for testing
@@ -562,6 +648,7 @@ func (suite *IngressWatcherTestSuite) createTestIngressWatcher() (*IngressWatche
 		suite.logger,
 		suite.kubeClientSet,
 		suite.createMockResolveFunc(),
+		nil,
 		scalertypes.Duration{},
 		"test-namespace",
 		"test-labels-filter",
@@ -0,0 +1,235 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TLSSecretWatcher watches the TLS secrets referenced by the spec.tls of the watched ingresses, and holds their
+// certificates by host. the certificates are rebuilt on any change of the ingresses or the secrets, so that renewed
+// certificates are served without a restart
+type TLSSecretWatcher struct {
+	ctx              context.Context
+	cancel           context.CancelFunc
+	logger           logger.Logger
+	ingressFactory   informers.SharedInformerFactory
+	secretFactory    informers.SharedInformerFactory
+	ingressInformer  cache.SharedIndexInformer
+	secretInformer   cache.SharedIndexInformer
+	rebuildLock      sync.Mutex
+	lock             sync.RWMutex
+	hostCertificates map[string]*tls.Certificate
+}
+
+func NewTLSSecretWatcher(
+	dlxCtx context.Context,
+	dlxLogger logger.Logger,
+	kubeClient kubernetes.Interface,
+	resyncInterval scalertypes.Duration,
+	namespace string,
+	labelSelector string,
+) (*TLSSecretWatcher, error) {
+	if resyncInterval.Duration == 0 {
+		resyncInterval = scalertypes.Duration{Duration: scalertypes.DefaultResyncInterval}
+	}
+
+	ctxWithCancel, cancel := context.WithCancel(dlxCtx)
+
+	// the label selector applies to the ingresses only, the secrets are selected by their type
+	ingressFactory := informers.NewSharedInformerFactoryWithOptions(
+		kubeClient,
+		resyncInterval.Duration,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = labelSelector
+		}),
+	)
+	secretFactory := informers.NewSharedInformerFactoryWithOptions(
+		kubeClient,
+		resyncInterval.Duration,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("type", string(v1.SecretTypeTLS)).String()
+		}),
+	)
+
+	tlsSecretWatcher := &TLSSecretWatcher{
+		ctx:              ctxWithCancel,
+		cancel:           cancel,
+		logger:           dlxLogger.GetChild("tls-secret-watcher"),
+		ingressFactory:   ingressFactory,
+		secretFactory:    secretFactory,
+		ingressInformer:  ingressFactory.Networking().V1().Ingresses().Informer(),
+		secretInformer:   secretFactory.Core().V1().Secrets().Informer(),
+		hostCertificates: map[string]*tls.Certificate{},
+	}
+
+	for _, informer := range []cache.SharedIndexInformer{
+		tlsSecretWatcher.ingressInformer,
+		tlsSecretWatcher.secretInformer,
+	} {
+		if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    tlsSecretWatcher.AddHandler,
+			UpdateFunc: tlsSecretWatcher.UpdateHandler,
+			DeleteFunc: tlsSecretWatcher.DeleteHandler,
+		}); err != nil {
+			return nil, errors.Wrap(err, "Failed to add event handlers to informer")
+		}
+	}
+
+	return tlsSecretWatcher, nil
+}
+
+func (tw *TLSSecretWatcher) Start() error {
+	tw.logger.Info("Starting TLS secret watcher")
+	tw.ingressFactory.Start(tw.ctx.Done())
+	tw.secretFactory.Start(tw.ctx.Done())
+
+	if !cache.WaitForCacheSync(tw.ctx.Done(), tw.ingressInformer.HasSynced, tw.secretInformer.HasSynced) {
+		return errors.New("Failed to sync TLS secret cache")
+	}
+
+	tw.rebuildHostCertificates()
+	tw.logger.Info("TLS secret watcher started successfully")
+
+	return nil
+}
+
+func (tw *TLSSecretWatcher) Stop() {
+	tw.logger.Info("Stopping TLS secret watcher")
+	tw.cancel()
+	tw.ingressFactory.Shutdown()
+	tw.secretFactory.Shutdown()
+}
+
+// GetCertificate returns the certificate of the given host, or of a wildcard host matching it
+func (tw *TLSSecretWatcher) GetCertificate(host string) (*tls.Certificate, bool) {
+	tw.lock.RLock()
+	defer tw.lock.RUnlock()
+
+	host = strings.ToLower(host)
+	if certificate, found := tw.hostCertificates[host]; found {
+		return certificate, true
+	}
+
+	// a wildcard matches a single label
+	if _, parentDomain, found := strings.Cut(host, "."); found {
+		if certificate, found := tw.hostCertificates["*."+parentDomain]; found {
+			return certificate, true
+		}
+	}
+
+	return nil, false
+}
+
+// --- ResourceEventHandler methods ---
+
+func (tw *TLSSecretWatcher) AddHandler(obj interface{}) {
+	tw.rebuildHostCertificates()
+}
+
+func (tw *TLSSecretWatcher) UpdateHandler(oldObj, newObj interface{}) {
+	oldMeta, oldOk := oldObj.(metav1.Object)
+	newMeta, newOk := newObj.(metav1.Object)
+
+	// skip periodic informer resyncs
+	if oldOk && newOk && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return
+	}
+
+	tw.rebuildHostCertificates()
+}
+
+func (tw *TLSSecretWatcher) DeleteHandler(obj interface{}) {
+	tw.rebuildHostCertificates()
+}
+
+// --- internal methods ---
+
+// rebuildHostCertificates maps the hosts of the ingresses to the certificates of their TLS secrets. changes are rare,
+// so everything is rebuilt from the informer caches rather than tracked incrementally. events of the initial listing
+// are skipped, as the certificates are built once the caches are synced
+func (tw *TLSSecretWatcher) rebuildHostCertificates() {
+	if !tw.ingressInformer.HasSynced() || !tw.secretInformer.HasSynced() {
+		return
+	}
+
+	// the ingress and secret informers call it concurrently
+	tw.rebuildLock.Lock()
+	defer tw.rebuildLock.Unlock()
+
+	certificates := map[string]*tls.Certificate{}
+	for _, obj := range tw.secretInformer.GetStore().List() {
+		secret, ok := obj.(*v1.Secret)
+		if !ok || secret.Type != v1.SecretTypeTLS {
+			continue
+		}
+
+		certificate, err := tls.X509KeyPair(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+		if err != nil {
+			tw.logger.WarnWith("Failed to parse TLS secret",
+				"secret", secret.Name,
+				"err", err.Error())
+			continue
+		}
+		certificates[secret.Name] = &certificate
+	}
+
+	hostCertificates := map[string]*tls.Certificate{}
+	for _, obj := range tw.ingressInformer.GetStore().List() {
+		ingress, ok := obj.(*networkingv1.Ingress)
+		if !ok {
+			continue
+		}
+
+		for _, ingressTLS := range ingress.Spec.TLS {
+			certificate, found := certificates[ingressTLS.SecretName]
+			if !found {
+				continue
+			}
+
+			for _, host := range ingressTLS.Hosts {
+				hostCertificates[strings.ToLower(host)] = certificate
+			}
+		}
+	}
+
+	tw.lock.Lock()
+	defer tw.lock.Unlock()
+
+	tw.hostCertificates = hostCertificates
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2025 Iguazio Systems Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License") with
+an addition restriction as set forth herein. You may not use this
+file except in compliance with the License. You may obtain a copy of
+the License at http://www.apache.org/licenses/LICENSE-2.0.
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+implied. See the License for the specific language governing
+permissions and limitations under the License.
+
+In addition, you may not use the software for any purposes that are
+illegal under applicable law, and the grant of the foregoing license
+under the Apache 2.0 license is conditioned upon your compliance with
+such restriction.
+*/
+
+package kube
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/v3io/scaler/pkg/scalertypes"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type TLSSecretWatcherTestSuite struct {
+	suite.Suite
+	logger        logger.Logger
+	kubeClientSet *fake.Clientset
+	namespace     string
+}
+
+func (suite *TLSSecretWatcherTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+	suite.kubeClientSet = fake.NewSimpleClientset()
+	suite.namespace = "default"
+}
+
+func (suite *TLSSecretWatcherTestSuite) TestGetCertificate() {
+	certificatePEM, keyPEM := suite.createCertificate("www.example.com")
+	suite.createSecret("example-tls", certificatePEM, keyPEM)
+	suite.createIngress("example", []networkingv1.IngressTLS{
+		{
+			Hosts:      []string{"www.example.com", "*.wild.example.com"},
+			SecretName: "example-tls",
+		}, {
+			Hosts:      []string{"missing.example.com"},
+			SecretName: "missing-tls",
+		},
+	})
+
+	tlsSecretWatcher := suite.createAndStartTLSSecretWatcher()
+	defer tlsSecretWatcher.Stop()
+
+	for _, testCase := range []struct {
+		name          string
+		host          string
+		expectedFound bool
+	}{
+		{
+			name:          "Exact host",
+			host:          "www.example.com",
+			expectedFound: true,
+		}, {
+			name:          "Host is case insensitive",
+			host:          "WWW.Example.com",
+			expectedFound: true,
+		}, {
+			name:          "Wildcard host",
+			host:          "a.wild.example.com",
+			expectedFound: true,
+		}, {
+			name:          "Wildcard matches a single label",
+			host:          "a.b.wild.example.com",
+			expectedFound: false,
+		}, {
+			name:          "Secret not found",
+			host:          "missing.example.com",
+			expectedFound: false,
+		}, {
+			name:          "Unknown host",
+			host:          "other.example.com",
+			expectedFound: false,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			certificate, found := tlsSecretWatcher.GetCertificate(testCase.host)
+			suite.Require().Equal(testCase.expectedFound, found)
+			if testCase.expectedFound {
+				suite.Require().NotNil(certificate)
+			}
+		})
+	}
+}
+
+func (suite *TLSSecretWatcherTestSuite) TestReloadOnChange() {
+	certificatePEM, keyPEM := suite.createCertificate("www.example.com")
+	secret := suite.createSecret("example-tls", certificatePEM, keyPEM)
+	ingress := suite.createIngress("example", []networkingv1.IngressTLS{
+		{
+			Hosts:      []string{"www.example.com"},
+			SecretName: "example-tls",
+		},
+	})
+
+	tlsSecretWatcher := suite.createAndStartTLSSecretWatcher()
+	defer tlsSecretWatcher.Stop()
+
+	certificate, found := tlsSecretWatcher.GetCertificate("www.example.com")
+	suite.Require().True(found)
+	initialCertificate := certificate.Certificate[0]
+
+	// a renewed secret is served without a restart
+	renewedCertificatePEM, renewedKeyPEM := suite.createCertificate("www.example.com")
+	secret.Data = map[string][]byte{
+		v1.TLSCertKey:       renewedCertificatePEM,
+		v1.TLSPrivateKeyKey: renewedKeyPEM,
+	}
+	secret.ResourceVersion = "2"
+	_, err := suite.kubeClientSet.CoreV1().Secrets(suite.namespace).Update(context.Background(),
+		secret,
+		metav1.UpdateOptions{})
+	suite.Require().NoError(err)
+
+	suite.Require().Eventually(func() bool {
+		certificate, found := tlsSecretWatcher.GetCertificate("www.example.com")
+		return found && string(certificate.Certificate[0]) != string(initialCertificate)
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// removing the ingress removes its hosts
+	err = suite.kubeClientSet.NetworkingV1().Ingresses(suite.namespace).Delete(context.Background(),
+		ingress.Name,
+		metav1.DeleteOptions{})
+	suite.Require().NoError(err)
+
+	suite.Require().Eventually(func() bool {
+		_, found := tlsSecretWatcher.GetCertificate("www.example.com")
+		return !found
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+// --- TLSSecretWatcherTestSuite suite methods ---
+
+func (suite *TLSSecretWatcherTestSuite) createAndStartTLSSecretWatcher() *TLSSecretWatcher {
+	tlsSecretWatcher, err := NewTLSSecretWatcher(context.Background(),
+		suite.logger,
+		suite.kubeClientSet,
+		scalertypes.Duration{},
+		suite.namespace,
+		"")
+	suite.Require().NoError(err)
+	suite.Require().NoError(tlsSecretWatcher.Start())
+	return tlsSecretWatcher
+}
+
+func (suite *TLSSecretWatcherTestSuite) createSecret(name string, certificatePEM, keyPEM []byte) *v1.Secret {
+	secret, err := suite.kubeClientSet.CoreV1().Secrets(suite.namespace).Create(context.Background(),
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       suite.namespace,
+				ResourceVersion: "1",
+			},
+			Type: v1.SecretTypeTLS,
+			Data: map[string][]byte{
+				v1.TLSCertKey:       certificatePEM,
+				v1.TLSPrivateKeyKey: keyPEM,
+			},
+		},
+		metav1.CreateOptions{})
+	suite.Require().NoError(err)
+	return secret
+}
+
+func (suite *TLSSecretWatcherTestSuite) createIngress(name string, ingressTLS []networkingv1.IngressTLS) *networkingv1.Ingress {
+	ingress, err := suite.kubeClientSet.NetworkingV1().Ingresses(suite.namespace).Create(context.Background(),
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: suite.namespace,
+			},
+			Spec: networkingv1.IngressSpec{
+				TLS: ingressTLS,
+			},
+		},
+		metav1.CreateOptions{})
+	suite.Require().NoError(err)
+	return ingress
+}
+
+func (suite *TLSSecretWatcherTestSuite) createCertificate(host string) ([]byte, []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	suite.Require().NoError(err)
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader,
+		&x509.Certificate{
+			SerialNumber: serialNumber,
+			Subject:      pkix.Name{CommonName: host},
+			DNSNames:     []string{host},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		},
+		&x509.Certificate{
+			SerialNumber: serialNumber,
+			Subject:      pkix.Name{CommonName: host},
+		},
+		&privateKey.PublicKey,
+		privateKey)
+	suite.Require().NoError(err)
+
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	suite.Require().NoError(err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSSecretWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(TLSSecretWatcherTestSuite))
+}
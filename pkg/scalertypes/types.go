@@ -37,6 +37,13 @@ type AutoScalerOptions struct {
 	Namespace     string
 	ScaleInterval Duration
 	GroupKind     schema.GroupKind
+
+	// resources whose last scale event is a started-but-not-completed transition for longer than
+	// this threshold are considered stuck and reconciled according to StuckScaleEventAction (0 disables)
+	StuckScaleEventThreshold Duration
+	StuckScaleEventAction    StuckScaleEventAction
+	KubeClientSet            kubernetes.Interface `json:"-"`
+	WarmResourcesBudget      WarmResourcesBudget
 }
 
 type ResourceScalerConfig struct {
@@ -51,12 +58,272 @@ const (
 	MultiTargetStrategyRandom  MultiTargetStrategy = "random"
 	MultiTargetStrategyPrimary MultiTargetStrategy = "primary"
 	MultiTargetStrategyCanary  MultiTargetStrategy = "canary"
+
+	// routes to the first target that starts successfully, in the order of the targets
+	MultiTargetStrategyFailover MultiTargetStrategy = "failover"
+
+	// routes all the requests of a session to the same target, according to the sticky session options
+	MultiTargetStrategySticky MultiTargetStrategy = "sticky"
+)
+
+type StuckScaleEventAction string
+
+const (
+	StuckScaleEventActionRetry         StuckScaleEventAction = "retry"
+	StuckScaleEventActionAlert         StuckScaleEventAction = "alert"
+	StuckScaleEventActionRetryAndAlert StuckScaleEventAction = "retryAndAlert"
+)
+
+func (a StuckScaleEventAction) ShouldRetry() bool {
+	return a == StuckScaleEventActionRetry || a == StuckScaleEventActionRetryAndAlert
+}
+
+func (a StuckScaleEventAction) ShouldAlert() bool {
+	return a == StuckScaleEventActionAlert || a == StuckScaleEventActionRetryAndAlert
+}
+
+type WarmResourcesBudgetScope string
+
+const (
+	WarmResourcesBudgetScopeNamespace WarmResourcesBudgetScope = "namespace"
+	WarmResourcesBudgetScopeGlobal    WarmResourcesBudgetScope = "global"
 )
 
+type WarmResourcesBudgetExceededPolicy string
+
+const (
+	WarmResourcesBudgetExceededPolicyReject WarmResourcesBudgetExceededPolicy = "reject"
+	WarmResourcesBudgetExceededPolicyQueue  WarmResourcesBudgetExceededPolicy = "queue"
+)
+
+// WarmResourcesBudget caps the total cost of resources that may be warm at once. the autoscaler enforces it by
+// scaling the least recently used resources to zero, and the DLX by rejecting or queueing wake-ups beyond it
+type WarmResourcesBudget struct {
+
+	// maximal total cost of warm resources per scope (0 disables the budget)
+	MaxWarmResources int
+	Scope            WarmResourcesBudgetScope
+
+	// what the DLX does with a wake-up that would exceed the budget
+	ExceededPolicy WarmResourcesBudgetExceededPolicy
+}
+
+func (b WarmResourcesBudget) Enabled() bool {
+	return b.MaxWarmResources > 0
+}
+
+// GetScopeKey returns the key of the budget the resource is accounted in
+func (b WarmResourcesBudget) GetScopeKey(resource Resource) string {
+	if b.Scope == WarmResourcesBudgetScopeGlobal {
+		return ""
+	}
+	return resource.Namespace
+}
+
+// GetWarmCost returns the total cost of the warm resources accounted in the given scope
+func (b WarmResourcesBudget) GetWarmCost(resources []Resource, scopeKey string) int {
+	warmCost := 0
+	for _, resource := range resources {
+		if resource.IsWarm() && b.GetScopeKey(resource) == scopeKey {
+			warmCost += resource.GetCost()
+		}
+	}
+	return warmCost
+}
+
 const (
 	DefaultResyncInterval = 30 * time.Second
 )
 
+// ProxyTransportOptions tune the transport shared by the DLX reverse proxies. zero values fall back to the defaults
+// of the standard library transport
+type ProxyTransportOptions struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     Duration
+	DialTimeout         Duration
+	TLSHandshakeTimeout Duration
+
+	// how long to wait for the response headers of a proxied request (0 for unbounded)
+	ResponseHeaderTimeout Duration
+}
+
+// ProxyRetryOptions configure how the DLX retries proxied requests that fail to reach a resource shortly after it
+// was woken up, while its service may still be warming up
+type ProxyRetryOptions struct {
+
+	// how long after a resource was woken up its requests are retried (0 disables retries)
+	WarmUpWindow Duration
+
+	// requests with larger bodies are not buffered, and therefore not retried
+	MaxBodySize int64
+
+	// the backoff between attempts starts at Backoff and doubles up to MaxBackoff
+	Backoff    Duration
+	MaxBackoff Duration
+}
+
+type ReadinessProbeType string
+
+const (
+	ReadinessProbeTypeNone ReadinessProbeType = "none"
+	ReadinessProbeTypeHTTP ReadinessProbeType = "http"
+	ReadinessProbeTypeTCP  ReadinessProbeType = "tcp"
+)
+
+// ReadinessProbeOptions configure how the DLX verifies that a resource service accepts requests after it was scaled
+// up, before releasing the requests waiting for it
+type ReadinessProbeOptions struct {
+	Type ReadinessProbeType
+
+	// path to GET with an http probe, which succeeds on a 2xx or 3xx response
+	Path string
+
+	// port of the resource service to probe (defaults to the DLX target port)
+	Port int
+
+	Interval Duration
+	Timeout  Duration
+}
+
+func (o ReadinessProbeOptions) Enabled() bool {
+	return o.Type != "" && o.Type != ReadinessProbeTypeNone
+}
+
+type StickySessionKeySource string
+
+const (
+	StickySessionKeySourceCookie StickySessionKeySource = "cookie"
+	StickySessionKeySourceHeader StickySessionKeySource = "header"
+
+	// the last X-Forwarded-For address - added by the ingress in front of the DLX - or else the remote address
+	StickySessionKeySourceClientIP StickySessionKeySource = "clientIP"
+)
+
+// StickySessionOptions configure how the sticky multi target strategy identifies a session. the session key is
+// hashed to a target, so a session keeps hitting the same target as long as the targets of its route do not change
+type StickySessionOptions struct {
+	KeySource StickySessionKeySource
+
+	// name of the cookie or header holding the session key
+	KeyName string
+
+	// when set, a cookie by this name pins the session to the target that served it, even if the targets change
+	AffinityCookieName   string
+	AffinityCookieMaxAge Duration
+}
+
+// CanaryOverrideOptions force requests to the canary target - the last target of a route - or to the primary target,
+// regardless of the multi target strategy. they follow the semantics of the nginx canary-by-header and
+// canary-by-cookie annotations, and the header takes precedence over the cookie
+type CanaryOverrideOptions struct {
+
+	// a request with this header set to "always" is routed to the canary, and set to "never" to the primary target
+	Header string
+
+	// when set, a request with the header set to this value is routed to the canary, instead of "always" and "never"
+	HeaderValue string
+
+	// a regular expression the header value is matched against, instead of HeaderValue. ignored if HeaderValue is set
+	HeaderPattern string
+
+	// a request with this cookie set to "always" is routed to the canary, and set to "never" to the primary target
+	Cookie string
+}
+
+// TCPProxyListener maps a port the DLX listens on for raw TCP connections to the resource they are proxied to
+type TCPProxyListener struct {
+	ListenAddress string
+	ResourceName  string
+
+	// port of the resource service the connections are proxied to
+	TargetPort int
+}
+
+// TLSOptions configure the DLX to terminate TLS itself. the certificate is selected by the server name (SNI) - from the
+// TLS secrets referenced by the watched ingresses, falling back to the certificate files
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+
+	// when set, the TLS secrets referenced by the spec.tls of the watched ingresses are served for their hosts
+	IngressSecrets bool
+
+	// how often the certificate files are checked for changes
+	ReloadInterval Duration
+}
+
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.IngressSecrets
+}
+
+// ServerOptions configure the http server of the DLX. zero durations leave the corresponding timeout unbounded
+type ServerOptions struct {
+	ReadTimeout       Duration
+	ReadHeaderTimeout Duration
+	IdleTimeout       Duration
+
+	// bounds the whole request, including the wait for its resource to wake up, so it must exceed the resource
+	// readiness timeout
+	WriteTimeout Duration
+
+	// 0 for the default of the http server (1MB)
+	MaxHeaderBytes int
+
+	DisableKeepAlives bool
+}
+
+// ErrorPageOptions configure the bodies of the responses to requests whose resources failed to wake up - an auto
+// refreshing page for browsers, and a JSON body for API clients. browsers may be shown the page once their resources
+// take too long to wake up, rather than once the wake-up fails
+type ErrorPageOptions struct {
+
+	// html/template files of the page, by default and per host. they are passed the Resource, the Reason, whether the
+	// resource is still Waking up, and the RetryAfter seconds until the page should be refreshed (0 if it should not)
+	TemplateFile      string
+	HostTemplateFiles map[string]string
+
+	// how often the page of a resource waking up is refreshed, unless the response has a Retry-After
+	RefreshInterval Duration
+
+	// how long GET and HEAD requests of browsers wait for their resources before they are shown the page of a resource
+	// waking up, which is refreshed until the resource is ready (0 to wait for the wake-up like any other request)
+	WakePageGracePeriod Duration
+}
+
+// AsyncRoute answers the requests to a route as soon as they trigger the wake-up of their resources, rather than once
+// the resources are ready, for clients that can't wait for a cold start - e.g. webhook senders. requests to resources
+// the readiness cache holds as ready are proxied as usual, so async routes require the readiness cache
+type AsyncRoute struct {
+
+	// the route matches requests to the host (any host when empty) whose path starts with the path prefix
+	Host       string
+	PathPrefix string
+
+	// the status the requests are answered with (202 when 0)
+	Status int
+
+	// when set, the requests are queued on disk and replayed to their target once it is ready. otherwise they are
+	// dropped once their wake-up is triggered
+	QueueRequests bool
+}
+
+type AsyncWakeOptions struct {
+	Routes []AsyncRoute
+
+	// the directory the queued requests are stored in, required when any of the routes queues requests
+	QueueDir string
+
+	// requests with larger bodies are rejected by the routes that queue requests (10MB when 0)
+	MaxQueuedBodySize int64
+}
+
+// ControlAPIOptions configure the control API of the DLX, which wakes resources and reports their wake-up status
+// under /_scaler/. the API is served only when a token is set, to requests bearing it
+type ControlAPIOptions struct {
+	Token string `json:"-"`
+}
+
 // ResolveTargetsFromIngressCallback defines a function that extracts a list of target identifiers
 // (e.g., names of services the Ingress routes traffic to) from a Kubernetes Ingress resource.
 //
@@ -76,6 +343,13 @@ const (
 // - Should handle nil or malformed Ingress objects gracefully and return an error in such cases
 type ResolveTargetsFromIngressCallback func(ingress *networkingv1.Ingress) ([]string, error)
 
+// ResolveTargetWeightsFromIngressCallback defines an optional function that resolves the routing weights of the targets
+// resolved from an ingress by ResolveTargetsFromIngressCallback, in the same order as the targets.
+//
+// A nil result means the ingress does not define weights, in which case the weights are taken from the nginx canary
+// weight annotations of the ingress, if present
+type ResolveTargetWeightsFromIngressCallback func(ingress *networkingv1.Ingress, targets []string) ([]int, error)
+
 type DLXOptions struct {
 	Namespace string
 
@@ -90,6 +364,53 @@ type DLXOptions struct {
 	ResolveTargetsFromIngressCallback ResolveTargetsFromIngressCallback `json:"-"`
 	ResyncInterval                    Duration
 	KubeClientSet                     kubernetes.Interface `json:"-"`
+
+	// optional, when not set target weights are resolved from the nginx canary weight annotations
+	ResolveTargetWeightsFromIngressCallback ResolveTargetWeightsFromIngressCallback `json:"-"`
+
+	// when set, a woken resource is considered ready only once all of its dependencies are ready as well
+	WaitForDependencies bool
+	WarmResourcesBudget WarmResourcesBudget
+
+	// when set, a woken resource is scaled to a replica per this many requests queued while it was waking,
+	// up to its max wake replicas (0 disables)
+	WakeRequestsPerReplica int
+	MaxWakeReplicas        int
+
+	// bounds on the requests waiting for resources to start, per resource and in total (0 for unbounded).
+	// requests beyond them are rejected with a Retry-After derived from the expected readiness time
+	MaxWaitingRequestsPerResource int
+	MaxWaitingRequests            int
+
+	// how long a started resource is considered ready without starting it again, unless its service endpoints
+	// change first (0 disables the readiness cache)
+	ReadinessCacheTTL Duration
+
+	ProxyTransport ProxyTransportOptions
+	ReadinessProbe ReadinessProbeOptions
+	ProxyRetry     ProxyRetryOptions
+
+	// when set, a request to a multi target route wakes only the target selected for it, instead of all the targets.
+	// the other targets are woken in the background if LazyWakeOtherTargets is set as well
+	WakeSelectedTargetOnly bool
+	LazyWakeOtherTargets   bool
+
+	StickySession  StickySessionOptions
+	CanaryOverride CanaryOverrideOptions
+
+	// when set, the DLX accepts HTTP/2 over cleartext (h2c) as well as over TLS, and proxies gRPC requests to their
+	// targets over cleartext HTTP/2
+	EnableHTTP2 bool
+
+	// raw TCP listeners, each waking and proxying to a single resource
+	TCPProxyListeners   []TCPProxyListener
+	TCPProxyDialTimeout Duration
+
+	TLS        TLSOptions
+	Server     ServerOptions
+	ErrorPage  ErrorPageOptions
+	AsyncWake  AsyncWakeOptions
+	ControlAPI ControlAPIOptions
 }
 
 type ResourceScaler interface {
@@ -106,6 +427,20 @@ type Resource struct {
 	ScaleResources     []ScaleResource `json:"scale_resources,omitempty"`
 	LastScaleEvent     *ScaleEvent     `json:"last_scale_event,omitempty"`
 	LastScaleEventTime *time.Time      `json:"last_scale_event_time,omitempty"`
+
+	// resources sharing a scale group are scaled to zero together and woken up together
+	ScaleGroup string `json:"scale_group,omitempty"`
+
+	// names of resources this resource calls, woken up in parallel to it by the DLX
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// weight of the resource in the warm resources budget (defaults to 1)
+	Cost int `json:"cost,omitempty"`
+
+	// replicas to scale the resource from zero to (defaults to 1), and the maximal replicas it may be scaled to
+	// while requests are queued for it in the DLX (defaults to DLXOptions.MaxWakeReplicas)
+	WakeReplicas    int `json:"wake_replicas,omitempty"`
+	MaxWakeReplicas int `json:"max_wake_replicas,omitempty"`
 }
 
 func (r Resource) String() string {
@@ -116,6 +451,32 @@ func (r Resource) String() string {
 	return string(out)
 }
 
+// IsScaledToZero returns true if the last scale event of the resource completed a scale to zero
+func (r Resource) IsScaledToZero() bool {
+	return r.LastScaleEvent != nil && *r.LastScaleEvent == ScaleToZeroCompletedScaleEvent
+}
+
+// IsWarm returns true unless the resource is scaled, or being scaled, to zero
+func (r Resource) IsWarm() bool {
+	return r.LastScaleEvent == nil ||
+		(*r.LastScaleEvent != ScaleToZeroStartedScaleEvent && *r.LastScaleEvent != ScaleToZeroCompletedScaleEvent)
+}
+
+func (r Resource) GetCost() int {
+	if r.Cost <= 0 {
+		return 1
+	}
+	return r.Cost
+}
+
+// GetWakeReplicas returns the amount of replicas to scale the resource from zero to
+func (r Resource) GetWakeReplicas() int {
+	if r.WakeReplicas <= 0 {
+		return 1
+	}
+	return r.WakeReplicas
+}
+
 type ScaleResource struct {
 	MetricName string   `json:"metric_name,omitempty"`
 	WindowSize Duration `json:"windows_size,omitempty"`